@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/jolisper/monkey/lexer"
+	"github.com/jolisper/monkey/parser"
+)
+
+// dumpAST implements the ast command. The JSON encoding of the program,
+// see package ast, goes to stdout and parser errors go to stderr.
+func dumpAST(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(stderr, "monkey: ast expects exactly one file\n\n%s", usage)
+		return exitUsage
+	}
+
+	name := flags.Arg(0)
+	src, err := readSource(name, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return exitInternal
+	}
+	if name == "-" {
+		name = stdinName
+	}
+
+	p := parser.New(lexer.NewWithFilename(name, src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, err := range p.Errors() {
+			fmt.Fprintln(stderr, err)
+		}
+		return exitError
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(program); err != nil {
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return exitInternal
+	}
+	return exitOK
+}
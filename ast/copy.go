@@ -0,0 +1,212 @@
+package ast
+
+// Copy returns a deep copy of the tree rooted at node, which can be changed,
+// e.g. by Modify, without changing node. Tokens and positions are kept. Nil
+// children stay nil.
+func Copy(node Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		return &Program{Statements: copyStatements(n.Statements)}
+
+	case *LetStatement:
+		return &LetStatement{Token: n.Token, Name: copyIdentifier(n.Name), Value: copyExpression(n.Value)}
+
+	case *ReturnStatement:
+		return &ReturnStatement{Token: n.Token, ReturnValue: copyExpression(n.ReturnValue)}
+
+	case *ExpressionStatement:
+		return &ExpressionStatement{Token: n.Token, Expression: copyExpression(n.Expression)}
+
+	case *ImportStatement:
+		return &ImportStatement{Token: n.Token, Path: copyString(n.Path)}
+
+	case *BlockStatement:
+		return copyBlock(n)
+
+	case *WhileStatement:
+		return &WhileStatement{Token: n.Token, Condition: copyExpression(n.Condition), Body: copyBlock(n.Body)}
+
+	case *ForStatement:
+		return &ForStatement{
+			Token:    n.Token,
+			Variable: copyIdentifier(n.Variable),
+			Iterable: copyExpression(n.Iterable),
+			Body:     copyBlock(n.Body),
+		}
+
+	case *BreakStatement:
+		c := *n
+		return &c
+
+	case *ContinueStatement:
+		c := *n
+		return &c
+
+	case *Identifier:
+		return copyIdentifier(n)
+
+	case *IntegerLiteral:
+		c := *n
+		return &c
+
+	case *FloatLiteral:
+		c := *n
+		return &c
+
+	case *StringLiteral:
+		c := *n
+		return &c
+
+	case *Boolean:
+		c := *n
+		return &c
+
+	case *PrefixExpression:
+		return &PrefixExpression{Token: n.Token, Operator: n.Operator, Right: copyExpression(n.Right)}
+
+	case *InfixExpression:
+		return &InfixExpression{
+			Token:    n.Token,
+			Left:     copyExpression(n.Left),
+			Operator: n.Operator,
+			Right:    copyExpression(n.Right),
+		}
+
+	case *ParenExpression:
+		return &ParenExpression{Token: n.Token, Expression: copyExpression(n.Expression), Rparen: n.Rparen}
+
+	case *LogicalExpression:
+		return &LogicalExpression{
+			Token:    n.Token,
+			Left:     copyExpression(n.Left),
+			Operator: n.Operator,
+			Right:    copyExpression(n.Right),
+		}
+
+	case *AssignExpression:
+		return &AssignExpression{
+			Token:    n.Token,
+			Target:   copyExpression(n.Target),
+			Operator: n.Operator,
+			Value:    copyExpression(n.Value),
+		}
+
+	case *IfExpression:
+		return &IfExpression{
+			Token:       n.Token,
+			Condition:   copyExpression(n.Condition),
+			Consequence: copyBlock(n.Consequence),
+			Alternative: copyBlock(n.Alternative),
+		}
+
+	case *FunctionLiteral:
+		return &FunctionLiteral{Token: n.Token, Parameters: copyParameters(n.Parameters), Rparen: n.Rparen, Body: copyBlock(n.Body)}
+
+	case *MacroLiteral:
+		return &MacroLiteral{Token: n.Token, Parameters: copyParameters(n.Parameters), Rparen: n.Rparen, Body: copyBlock(n.Body)}
+
+	case *CallExpression:
+		return &CallExpression{
+			Token:     n.Token,
+			Function:  copyExpression(n.Function),
+			Arguments: copyExpressions(n.Arguments),
+			Rparen:    n.Rparen,
+		}
+
+	case *ArrayLiteral:
+		return &ArrayLiteral{Token: n.Token, Elements: copyExpressions(n.Elements), Rbrack: n.Rbrack}
+
+	case *IndexExpression:
+		return &IndexExpression{
+			Token:  n.Token,
+			Left:   copyExpression(n.Left),
+			Index:  copyExpression(n.Index),
+			Rbrack: n.Rbrack,
+		}
+
+	case *MemberExpression:
+		return &MemberExpression{Token: n.Token, Left: copyExpression(n.Left), Member: copyIdentifier(n.Member)}
+
+	case *HashLiteral:
+		var pairs []HashPair
+		if n.Pairs != nil {
+			pairs = make([]HashPair, len(n.Pairs))
+			for i, pair := range n.Pairs {
+				pairs[i] = HashPair{Key: copyExpression(pair.Key), Value: copyExpression(pair.Value)}
+			}
+		}
+		return &HashLiteral{Token: n.Token, Pairs: pairs, Rbrace: n.Rbrace}
+	}
+
+	return node
+}
+
+func copyIdentifier(ident *Identifier) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	c := *ident
+	return &c
+}
+
+func copyString(s *StringLiteral) *StringLiteral {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func copyBlock(block *BlockStatement) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	return &BlockStatement{Token: block.Token, Statements: copyStatements(block.Statements), Rbrace: block.Rbrace}
+}
+
+func copyStatement(s Statement) Statement {
+	if s == nil {
+		return nil
+	}
+	return Copy(s).(Statement)
+}
+
+func copyExpression(e Expression) Expression {
+	if e == nil {
+		return nil
+	}
+	return Copy(e).(Expression)
+}
+
+func copyStatements(stmts []Statement) []Statement {
+	if stmts == nil {
+		return nil
+	}
+	c := make([]Statement, len(stmts))
+	for i, s := range stmts {
+		c[i] = copyStatement(s)
+	}
+	return c
+}
+
+func copyExpressions(exps []Expression) []Expression {
+	if exps == nil {
+		return nil
+	}
+	c := make([]Expression, len(exps))
+	for i, e := range exps {
+		c[i] = copyExpression(e)
+	}
+	return c
+}
+
+func copyParameters(params []*Identifier) []*Identifier {
+	if params == nil {
+		return nil
+	}
+	c := make([]*Identifier, len(params))
+	for i, p := range params {
+		c[i] = copyIdentifier(p)
+	}
+	return c
+}
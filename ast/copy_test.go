@@ -0,0 +1,70 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/jolisper/monkey/ast"
+)
+
+func TestCopy(t *testing.T) {
+	inputs := []string{
+		"let a = -1; const b = 1.5; return a && b;",
+		"if (1) { 1 } else { 1 }; if (1) { 1 }",
+		"fn(x) { 1 }(1); macro(x) { quote(unquote(x) + 1) }",
+		`[1, "1", true][1]; {1: 1}`,
+		"while (1) { a += 1; break; continue; }",
+		"for (x in [1]) { x[0] = 1 }",
+		`import "math"; math.abs(1).x`,
+	}
+
+	for _, input := range inputs {
+		program := parse(t, input)
+		original := program.String()
+
+		copied := ast.Copy(program)
+		if copied.String() != original {
+			t.Errorf("wrong copy of %q. want=%q, got=%q", input, original, copied.String())
+		}
+
+		// Changing every node of the copy, even in place, leaves the
+		// original alone.
+		ast.Modify(copied, func(node ast.Node) ast.Node {
+			switch n := node.(type) {
+			case *ast.IntegerLiteral:
+				n.Value = 2
+				n.Token.Literal = "2"
+			case *ast.Identifier:
+				n.Value += "_"
+				n.Token.Literal += "_"
+			case *ast.BlockStatement:
+				n.Statements = append(n.Statements, &ast.BreakStatement{})
+			}
+			return node
+		})
+		if program.String() != original {
+			t.Errorf("copy of %q shares nodes with the original. got=%q", input, program.String())
+		}
+
+		var want, got []string
+		ast.Inspect(program, func(n ast.Node) bool {
+			if n != nil {
+				want = append(want, n.Pos().String())
+			}
+			return true
+		})
+		ast.Inspect(ast.Copy(program), func(n ast.Node) bool {
+			if n != nil {
+				got = append(got, n.Pos().String())
+			}
+			return true
+		})
+		if len(got) != len(want) {
+			t.Fatalf("wrong number of nodes in the copy of %q. want=%d, got=%d", input, len(want), len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("wrong position of node %d in the copy of %q. want=%s, got=%s", i, input, want[i], got[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,875 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/jolisper/monkey/token"
+)
+
+// JSON encoding of syntax trees, for tools that are not written in Go.
+//
+// Every node is an object with a "kind", the name of its type like
+// "InfixExpression", and its span: "start" is the position of its first
+// char and "end" the position right after its last one, both objects with
+// an "offset" in bytes, a "line" and a "column". Nodes built without
+// positions have no span. The fields of the node follow, named after the
+// fields of its type:
+//
+//	Program              filename, statements
+//	LetStatement         constant, name, value
+//	ReturnStatement      returnValue
+//	ExpressionStatement  expression
+//	ImportStatement      path
+//	BlockStatement       statements
+//	WhileStatement       condition, body
+//	ForStatement         variable, iterable, body
+//	BreakStatement
+//	ContinueStatement
+//	Identifier           value
+//	IntegerLiteral       value, literal
+//	FloatLiteral         value, literal
+//	StringLiteral        value
+//	Boolean              value
+//	PrefixExpression     operator, right
+//	InfixExpression      left, operator, right
+//	ParenExpression      expression
+//	LogicalExpression    left, operator, right
+//	AssignExpression     target, operator, value
+//	IfExpression         condition, consequence, alternative
+//	FunctionLiteral      parameters, body
+//	MacroLiteral         parameters, body
+//	CallExpression       function, arguments
+//	ArrayLiteral         elements
+//	IndexExpression      left, index
+//	MemberExpression     left, member
+//	HashLiteral          pairs, each an object with a key and a value
+//
+// The value of a number is a JSON number of any size, and its literal is
+// the text of the number in the source. The filename of a program is the
+// file its positions refer to; the positions of the other nodes leave it
+// out. An else-less if expression has no alternative.
+
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// An object being encoded, its members in the order they were set. A nil
+// *jsonNode encodes as null.
+type jsonNode struct {
+	names  []string
+	values []interface{}
+}
+
+func (n *jsonNode) set(name string, value interface{}) {
+	n.names = append(n.names, name)
+	n.values = append(n.values, value)
+}
+
+func (n *jsonNode) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range n.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := marshal(name)
+		value, err := marshal(n.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Like json.Marshal, but leaves the <, > and & of source code unescaped.
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// MarshalNode returns the JSON encoding of the tree rooted at node.
+func MarshalNode(node Node) ([]byte, error) {
+	return marshal(encodeNode(node))
+}
+
+// UnmarshalNode decodes a tree encoded by MarshalNode. The tokens of the
+// decoded nodes are rebuilt from their kind and fields; the ones that do
+// not start their node, like the operator of an infix expression, have no
+// position.
+func UnmarshalNode(data []byte) (Node, error) {
+	d := &decoder{}
+	node, err := d.node(data)
+	if err != nil {
+		return nil, fmt.Errorf("ast: %w", err)
+	}
+	if node == nil {
+		return nil, fmt.Errorf("ast: no node to decode")
+	}
+	return node, nil
+}
+
+func (p *Program) MarshalJSON() ([]byte, error) {
+	return MarshalNode(p)
+}
+
+func (p *Program) UnmarshalJSON(data []byte) error {
+	node, err := UnmarshalNode(data)
+	if err != nil {
+		return err
+	}
+
+	program, ok := node.(*Program)
+	if !ok {
+		return fmt.Errorf("ast: %s is not a Program", kindOf(node))
+	}
+
+	*p = *program
+	return nil
+}
+
+// Encoding:
+
+func encodeNode(node Node) *jsonNode {
+	if isNil(node) {
+		return nil
+	}
+
+	out := &jsonNode{}
+	out.set("kind", kindOf(node))
+	if start, end := node.Pos(), node.End(); start.IsValid() && end.IsValid() {
+		out.set("start", jsonPosition{start.Offset, start.Line, start.Column})
+		out.set("end", jsonPosition{end.Offset, end.Line, end.Column})
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		if filename := n.Pos().Filename; filename != "" {
+			out.set("filename", filename)
+		}
+		out.set("statements", encodeStatements(n.Statements))
+
+	case *LetStatement:
+		out.set("constant", n.IsConst())
+		out.set("name", encodeNode(n.Name))
+		out.set("value", encodeNode(n.Value))
+
+	case *ReturnStatement:
+		out.set("returnValue", encodeNode(n.ReturnValue))
+
+	case *ExpressionStatement:
+		out.set("expression", encodeNode(n.Expression))
+
+	case *ImportStatement:
+		out.set("path", encodeNode(n.Path))
+
+	case *BlockStatement:
+		out.set("statements", encodeStatements(n.Statements))
+
+	case *WhileStatement:
+		out.set("condition", encodeNode(n.Condition))
+		out.set("body", encodeNode(n.Body))
+
+	case *ForStatement:
+		out.set("variable", encodeNode(n.Variable))
+		out.set("iterable", encodeNode(n.Iterable))
+		out.set("body", encodeNode(n.Body))
+
+	case *BreakStatement, *ContinueStatement:
+
+	case *Identifier:
+		out.set("value", n.Value)
+
+	case *IntegerLiteral:
+		if n.Big != nil {
+			out.set("value", json.Number(n.Big.String()))
+		} else {
+			out.set("value", json.Number(strconv.FormatInt(n.Value, 10)))
+		}
+		out.set("literal", n.Token.Literal)
+
+	case *FloatLiteral:
+		out.set("value", json.Number(strconv.FormatFloat(n.Value, 'g', -1, 64)))
+		out.set("literal", n.Token.Literal)
+
+	case *StringLiteral:
+		out.set("value", n.Value)
+
+	case *Boolean:
+		out.set("value", n.Value)
+
+	case *PrefixExpression:
+		out.set("operator", n.Operator)
+		out.set("right", encodeNode(n.Right))
+
+	case *InfixExpression:
+		out.set("left", encodeNode(n.Left))
+		out.set("operator", n.Operator)
+		out.set("right", encodeNode(n.Right))
+
+	case *ParenExpression:
+		out.set("expression", encodeNode(n.Expression))
+
+	case *LogicalExpression:
+		out.set("left", encodeNode(n.Left))
+		out.set("operator", n.Operator)
+		out.set("right", encodeNode(n.Right))
+
+	case *AssignExpression:
+		out.set("target", encodeNode(n.Target))
+		out.set("operator", n.Operator)
+		out.set("value", encodeNode(n.Value))
+
+	case *IfExpression:
+		out.set("condition", encodeNode(n.Condition))
+		out.set("consequence", encodeNode(n.Consequence))
+		if n.Alternative != nil {
+			out.set("alternative", encodeNode(n.Alternative))
+		}
+
+	case *FunctionLiteral:
+		out.set("parameters", encodeParameters(n.Parameters))
+		out.set("body", encodeNode(n.Body))
+
+	case *MacroLiteral:
+		out.set("parameters", encodeParameters(n.Parameters))
+		out.set("body", encodeNode(n.Body))
+
+	case *CallExpression:
+		out.set("function", encodeNode(n.Function))
+		out.set("arguments", encodeExpressions(n.Arguments))
+
+	case *ArrayLiteral:
+		out.set("elements", encodeExpressions(n.Elements))
+
+	case *IndexExpression:
+		out.set("left", encodeNode(n.Left))
+		out.set("index", encodeNode(n.Index))
+
+	case *MemberExpression:
+		out.set("left", encodeNode(n.Left))
+		out.set("member", encodeNode(n.Member))
+
+	case *HashLiteral:
+		pairs := make([]interface{}, len(n.Pairs))
+		for i, pair := range n.Pairs {
+			pairs[i] = map[string]interface{}{
+				"key":   encodeNode(pair.Key),
+				"value": encodeNode(pair.Value),
+			}
+		}
+		out.set("pairs", pairs)
+	}
+
+	return out
+}
+
+func encodeStatements(stmts []Statement) []interface{} {
+	out := make([]interface{}, len(stmts))
+	for i, s := range stmts {
+		out[i] = encodeNode(s)
+	}
+	return out
+}
+
+func encodeParameters(params []*Identifier) []interface{} {
+	out := make([]interface{}, len(params))
+	for i, p := range params {
+		out[i] = encodeNode(p)
+	}
+	return out
+}
+
+func encodeExpressions(exps []Expression) []interface{} {
+	out := make([]interface{}, len(exps))
+	for i, e := range exps {
+		out[i] = encodeNode(e)
+	}
+	return out
+}
+
+// kindOf returns the name of the type of node, without package and
+// pointer, like "Identifier".
+func kindOf(node Node) string {
+	switch node.(type) {
+	case *Program:
+		return "Program"
+	case *LetStatement:
+		return "LetStatement"
+	case *ReturnStatement:
+		return "ReturnStatement"
+	case *ExpressionStatement:
+		return "ExpressionStatement"
+	case *ImportStatement:
+		return "ImportStatement"
+	case *BlockStatement:
+		return "BlockStatement"
+	case *WhileStatement:
+		return "WhileStatement"
+	case *ForStatement:
+		return "ForStatement"
+	case *BreakStatement:
+		return "BreakStatement"
+	case *ContinueStatement:
+		return "ContinueStatement"
+	case *Identifier:
+		return "Identifier"
+	case *IntegerLiteral:
+		return "IntegerLiteral"
+	case *FloatLiteral:
+		return "FloatLiteral"
+	case *StringLiteral:
+		return "StringLiteral"
+	case *Boolean:
+		return "Boolean"
+	case *PrefixExpression:
+		return "PrefixExpression"
+	case *InfixExpression:
+		return "InfixExpression"
+	case *ParenExpression:
+		return "ParenExpression"
+	case *LogicalExpression:
+		return "LogicalExpression"
+	case *AssignExpression:
+		return "AssignExpression"
+	case *IfExpression:
+		return "IfExpression"
+	case *FunctionLiteral:
+		return "FunctionLiteral"
+	case *MacroLiteral:
+		return "MacroLiteral"
+	case *CallExpression:
+		return "CallExpression"
+	case *ArrayLiteral:
+		return "ArrayLiteral"
+	case *IndexExpression:
+		return "IndexExpression"
+	case *MemberExpression:
+		return "MemberExpression"
+	case *HashLiteral:
+		return "HashLiteral"
+	}
+	return fmt.Sprintf("%T", node)
+}
+
+// Reports whether node is nil, including a nil pointer in a Node.
+func isNil(node Node) bool {
+	switch n := node.(type) {
+	case nil:
+		return true
+	case *Identifier:
+		return n == nil
+	case *StringLiteral:
+		return n == nil
+	case *BlockStatement:
+		return n == nil
+	}
+	return false
+}
+
+// Decoding:
+
+type decoder struct {
+	filename string // of the program being decoded
+}
+
+// An object being decoded, its members still encoded.
+type jsonObject map[string]json.RawMessage
+
+// Decodes a node, nil for a JSON null.
+func (d *decoder) node(data json.RawMessage) (Node, error) {
+	var obj jsonObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, nil
+	}
+
+	var kind string
+	if err := obj.field("kind", &kind); err != nil {
+		return nil, err
+	}
+
+	var start, end token.Position
+	if _, ok := obj["start"]; ok {
+		if err := d.position(obj, "start", &start); err != nil {
+			return nil, err
+		}
+		if err := d.position(obj, "end", &end); err != nil {
+			return nil, err
+		}
+	}
+
+	node, err := d.decodeKind(kind, obj, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", kind, err)
+	}
+	return node, nil
+}
+
+func (d *decoder) decodeKind(kind string, obj jsonObject, start, end token.Position) (Node, error) {
+	var err error
+
+	switch kind {
+	case "Program":
+		if _, ok := obj["filename"]; ok {
+			if err := obj.field("filename", &d.filename); err != nil {
+				return nil, err
+			}
+			// start and end were decoded before the file name was known
+			start.Filename, end.Filename = d.filename, d.filename
+		}
+		n := &Program{}
+		n.Statements, err = d.statements(obj, "statements")
+		return n, err
+
+	case "LetStatement":
+		var constant bool
+		if _, ok := obj["constant"]; ok {
+			if err := obj.field("constant", &constant); err != nil {
+				return nil, err
+			}
+		}
+		n := &LetStatement{Token: keyword(token.LET, "let", start)}
+		if constant {
+			n.Token = keyword(token.CONST, "const", start)
+		}
+		if n.Name, err = d.identifier(obj, "name"); err != nil {
+			return nil, err
+		}
+		n.Value, err = d.expression(obj, "value")
+		return n, err
+
+	case "ReturnStatement":
+		n := &ReturnStatement{Token: keyword(token.RETURN, "return", start)}
+		n.ReturnValue, err = d.expression(obj, "returnValue")
+		return n, err
+
+	case "ExpressionStatement":
+		n := &ExpressionStatement{}
+		if n.Expression, err = d.expression(obj, "expression"); err != nil {
+			return nil, err
+		}
+		n.Token = token.Token{Literal: n.Expression.TokenLiteral(), Pos: start}
+		return n, nil
+
+	case "ImportStatement":
+		n := &ImportStatement{Token: keyword(token.IMPORT, "import", start)}
+		node, err := d.child(obj, "path")
+		if err != nil {
+			return nil, err
+		}
+		path, ok := node.(*StringLiteral)
+		if !ok {
+			return nil, fmt.Errorf("path: %s is not a StringLiteral", kindOf(node))
+		}
+		n.Path = path
+		return n, nil
+
+	case "BlockStatement":
+		n := &BlockStatement{Token: keyword(token.LBRACE, "{", start), Rbrace: before(end)}
+		n.Statements, err = d.statements(obj, "statements")
+		return n, err
+
+	case "WhileStatement":
+		n := &WhileStatement{Token: keyword(token.WHILE, "while", start)}
+		if n.Condition, err = d.expression(obj, "condition"); err != nil {
+			return nil, err
+		}
+		n.Body, err = d.block(obj, "body")
+		return n, err
+
+	case "ForStatement":
+		n := &ForStatement{Token: keyword(token.FOR, "for", start)}
+		if n.Variable, err = d.identifier(obj, "variable"); err != nil {
+			return nil, err
+		}
+		if n.Iterable, err = d.expression(obj, "iterable"); err != nil {
+			return nil, err
+		}
+		n.Body, err = d.block(obj, "body")
+		return n, err
+
+	case "BreakStatement":
+		return &BreakStatement{Token: keyword(token.BREAK, "break", start)}, nil
+
+	case "ContinueStatement":
+		return &ContinueStatement{Token: keyword(token.CONTINUE, "continue", start)}, nil
+
+	case "Identifier":
+		n := &Identifier{}
+		if err := obj.field("value", &n.Value); err != nil {
+			return nil, err
+		}
+		n.Token = token.Token{Type: token.IDENT, Literal: n.Value, Pos: start, End: end}
+		return n, nil
+
+	case "IntegerLiteral":
+		n := &IntegerLiteral{}
+		var value json.Number
+		if err := obj.field("value", &value); err != nil {
+			return nil, err
+		}
+		i, ok := new(big.Int).SetString(value.String(), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %s", value)
+		}
+		if i.IsInt64() {
+			n.Value = i.Int64()
+		} else {
+			n.Big = i
+		}
+		n.Token = token.Token{Type: token.INT, Literal: value.String(), Pos: start, End: end}
+		err = d.literal(obj, &n.Token)
+		return n, err
+
+	case "FloatLiteral":
+		n := &FloatLiteral{}
+		var value json.Number
+		if err := obj.field("value", &value); err != nil {
+			return nil, err
+		}
+		if n.Value, err = value.Float64(); err != nil {
+			return nil, err
+		}
+		n.Token = token.Token{Type: token.FLOAT, Literal: value.String(), Pos: start, End: end}
+		err = d.literal(obj, &n.Token)
+		return n, err
+
+	case "StringLiteral":
+		n := &StringLiteral{}
+		if err := obj.field("value", &n.Value); err != nil {
+			return nil, err
+		}
+		n.Token = token.Token{Type: token.STRING, Literal: n.Value, Pos: start, End: end}
+		return n, nil
+
+	case "Boolean":
+		n := &Boolean{}
+		if err := obj.field("value", &n.Value); err != nil {
+			return nil, err
+		}
+		if n.Value {
+			n.Token = token.Token{Type: token.TRUE, Literal: "true", Pos: start, End: end}
+		} else {
+			n.Token = token.Token{Type: token.FALSE, Literal: "false", Pos: start, End: end}
+		}
+		return n, nil
+
+	case "PrefixExpression":
+		n := &PrefixExpression{}
+		if err := obj.field("operator", &n.Operator); err != nil {
+			return nil, err
+		}
+		n.Token = keyword(token.TokenType(n.Operator), n.Operator, start)
+		n.Right, err = d.expression(obj, "right")
+		return n, err
+
+	case "InfixExpression":
+		n := &InfixExpression{}
+		if err := obj.field("operator", &n.Operator); err != nil {
+			return nil, err
+		}
+		n.Token = operator(n.Operator)
+		if n.Left, err = d.expression(obj, "left"); err != nil {
+			return nil, err
+		}
+		n.Right, err = d.expression(obj, "right")
+		return n, err
+
+	case "ParenExpression":
+		n := &ParenExpression{Token: keyword(token.LPAREN, "(", start), Rparen: before(end)}
+		n.Expression, err = d.expression(obj, "expression")
+		return n, err
+
+	case "LogicalExpression":
+		n := &LogicalExpression{}
+		if err := obj.field("operator", &n.Operator); err != nil {
+			return nil, err
+		}
+		n.Token = operator(n.Operator)
+		if n.Left, err = d.expression(obj, "left"); err != nil {
+			return nil, err
+		}
+		n.Right, err = d.expression(obj, "right")
+		return n, err
+
+	case "AssignExpression":
+		n := &AssignExpression{}
+		if err := obj.field("operator", &n.Operator); err != nil {
+			return nil, err
+		}
+		n.Token = operator(n.Operator)
+		if n.Target, err = d.expression(obj, "target"); err != nil {
+			return nil, err
+		}
+		n.Value, err = d.expression(obj, "value")
+		return n, err
+
+	case "IfExpression":
+		n := &IfExpression{Token: keyword(token.IF, "if", start)}
+		if n.Condition, err = d.expression(obj, "condition"); err != nil {
+			return nil, err
+		}
+		if n.Consequence, err = d.block(obj, "consequence"); err != nil {
+			return nil, err
+		}
+		if _, ok := obj["alternative"]; ok {
+			n.Alternative, err = d.block(obj, "alternative")
+		}
+		return n, err
+
+	case "FunctionLiteral":
+		n := &FunctionLiteral{Token: keyword(token.FUNCTION, "fn", start)}
+		if n.Parameters, err = d.parameters(obj, "parameters"); err != nil {
+			return nil, err
+		}
+		n.Body, err = d.block(obj, "body")
+		return n, err
+
+	case "MacroLiteral":
+		n := &MacroLiteral{Token: keyword(token.MACRO, "macro", start)}
+		if n.Parameters, err = d.parameters(obj, "parameters"); err != nil {
+			return nil, err
+		}
+		n.Body, err = d.block(obj, "body")
+		return n, err
+
+	case "CallExpression":
+		n := &CallExpression{Token: operator("("), Rparen: before(end)}
+		if n.Function, err = d.expression(obj, "function"); err != nil {
+			return nil, err
+		}
+		n.Arguments, err = d.expressions(obj, "arguments")
+		return n, err
+
+	case "ArrayLiteral":
+		n := &ArrayLiteral{Token: keyword(token.LBRACKET, "[", start), Rbrack: before(end)}
+		n.Elements, err = d.expressions(obj, "elements")
+		return n, err
+
+	case "IndexExpression":
+		n := &IndexExpression{Token: operator("["), Rbrack: before(end)}
+		if n.Left, err = d.expression(obj, "left"); err != nil {
+			return nil, err
+		}
+		n.Index, err = d.expression(obj, "index")
+		return n, err
+
+	case "MemberExpression":
+		n := &MemberExpression{Token: operator(".")}
+		if n.Left, err = d.expression(obj, "left"); err != nil {
+			return nil, err
+		}
+		n.Member, err = d.identifier(obj, "member")
+		return n, err
+
+	case "HashLiteral":
+		n := &HashLiteral{Token: keyword(token.LBRACE, "{", start), Rbrace: before(end)}
+		var pairs []jsonObject
+		if err := obj.field("pairs", &pairs); err != nil {
+			return nil, err
+		}
+		n.Pairs = []HashPair{}
+		for _, pair := range pairs {
+			key, err := d.expression(pair, "key")
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.expression(pair, "value")
+			if err != nil {
+				return nil, err
+			}
+			n.Pairs = append(n.Pairs, HashPair{Key: key, Value: value})
+		}
+		return n, nil
+	}
+
+	return nil, fmt.Errorf("unknown node kind")
+}
+
+// Decodes the member name of obj into v, which must be present.
+func (obj jsonObject) field(name string, v interface{}) error {
+	data, ok := obj[name]
+	if !ok {
+		return fmt.Errorf("missing %s", name)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func (d *decoder) position(obj jsonObject, name string, pos *token.Position) error {
+	var p jsonPosition
+	if err := obj.field(name, &p); err != nil {
+		return err
+	}
+	*pos = token.Position{Filename: d.filename, Offset: p.Offset, Line: p.Line, Column: p.Column}
+	return nil
+}
+
+// Sets the literal of tok from the literal member of obj, when present.
+func (d *decoder) literal(obj jsonObject, tok *token.Token) error {
+	if _, ok := obj["literal"]; !ok {
+		return nil
+	}
+	return obj.field("literal", &tok.Literal)
+}
+
+func (d *decoder) expression(obj jsonObject, name string) (Expression, error) {
+	node, err := d.child(obj, name)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := node.(Expression)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s is not an expression", name, kindOf(node))
+	}
+	return e, nil
+}
+
+func (d *decoder) identifier(obj jsonObject, name string) (*Identifier, error) {
+	node, err := d.child(obj, name)
+	if err != nil {
+		return nil, err
+	}
+	ident, ok := node.(*Identifier)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s is not an Identifier", name, kindOf(node))
+	}
+	return ident, nil
+}
+
+func (d *decoder) block(obj jsonObject, name string) (*BlockStatement, error) {
+	node, err := d.child(obj, name)
+	if err != nil {
+		return nil, err
+	}
+	block, ok := node.(*BlockStatement)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s is not a BlockStatement", name, kindOf(node))
+	}
+	return block, nil
+}
+
+// Decodes the member name of obj, a node that must not be null.
+func (d *decoder) child(obj jsonObject, name string) (Node, error) {
+	data, ok := obj[name]
+	if !ok {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	node, err := d.node(data)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, fmt.Errorf("%s is null", name)
+	}
+	return node, nil
+}
+
+func (d *decoder) statements(obj jsonObject, name string) ([]Statement, error) {
+	var list []json.RawMessage
+	if err := obj.field(name, &list); err != nil {
+		return nil, err
+	}
+
+	stmts := []Statement{}
+	for _, data := range list {
+		node, err := d.node(data)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := node.(Statement)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not a statement", name, kindOf(node))
+		}
+		stmts = append(stmts, s)
+	}
+	return stmts, nil
+}
+
+func (d *decoder) parameters(obj jsonObject, name string) ([]*Identifier, error) {
+	var list []json.RawMessage
+	if err := obj.field(name, &list); err != nil {
+		return nil, err
+	}
+
+	params := []*Identifier{}
+	for _, data := range list {
+		node, err := d.node(data)
+		if err != nil {
+			return nil, err
+		}
+		ident, ok := node.(*Identifier)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not an Identifier", name, kindOf(node))
+		}
+		params = append(params, ident)
+	}
+	return params, nil
+}
+
+func (d *decoder) expressions(obj jsonObject, name string) ([]Expression, error) {
+	var list []json.RawMessage
+	if err := obj.field(name, &list); err != nil {
+		return nil, err
+	}
+
+	exps := []Expression{}
+	for _, data := range list {
+		node, err := d.node(data)
+		if err != nil {
+			return nil, err
+		}
+		e, ok := node.(Expression)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not an expression", name, kindOf(node))
+		}
+		exps = append(exps, e)
+	}
+	return exps, nil
+}
+
+// keyword returns the token of type t and text literal starting at pos.
+func keyword(t token.TokenType, literal string, pos token.Position) token.Token {
+	end := pos
+	if end.IsValid() {
+		end.Offset += len(literal)
+		end.Column += utf8.RuneCountInString(literal)
+	}
+	return token.Token{Type: t, Literal: literal, Pos: pos, End: end}
+}
+
+// operator returns the token of an operator whose position is unknown.
+// The type of an operator token is its text.
+func operator(op string) token.Token {
+	return token.Token{Type: token.TokenType(op), Literal: op}
+}
+
+// before returns the position of the one-byte token ending at pos.
+func before(pos token.Position) token.Position {
+	if !pos.IsValid() {
+		return pos
+	}
+	pos.Offset--
+	pos.Column--
+	return pos
+}
@@ -0,0 +1,168 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jolisper/monkey/ast"
+	"github.com/jolisper/monkey/lexer"
+	"github.com/jolisper/monkey/parser"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	inputs := []string{
+		"let a = -1; const b = 2.5e3;",
+		"return a && b || !c;",
+		"if (a) { 1 } else { 2 }; if (b) { 3 }",
+		"fn(x, y) { x + y * 2 }(1, 2);",
+		`f("s\n", [true, false])[0]`,
+		`{"k": v, 1: [], true: {}}`,
+		"while (c) { a += 1; xs[0] = 2; break; }",
+		"for (x in xs) { continue; }",
+		"99999999999999999999 + 0.1",
+		"fn() { fn(a) { a }; }",
+		"let m = macro(a, b) { quote(unquote(a) + unquote(b)) };",
+		`import "lib/math"; math.sqrt(x).y[0]`,
+		"(1 + 2) * 3; ((a)) = -(b);",
+	}
+
+	for _, input := range inputs {
+		p := parser.New(lexer.NewWithFilename("test.mk", input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", input, p.Errors())
+		}
+
+		data, err := json.Marshal(program)
+		if err != nil {
+			t.Fatalf("json.Marshal(%q) failed: %s", input, err)
+		}
+
+		var decoded ast.Program
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("json.Unmarshal for %q failed: %s\n%s", input, err, data)
+		}
+
+		if decoded.String() != program.String() {
+			t.Errorf("wrong program for %q. want=%q, got=%q", input, program.String(), decoded.String())
+		}
+
+		// Every node keeps its span.
+		var want, got []string
+		ast.Inspect(program, func(n ast.Node) bool {
+			if n != nil {
+				want = append(want, n.Pos().String()+"-"+n.End().String())
+			}
+			return true
+		})
+		ast.Inspect(&decoded, func(n ast.Node) bool {
+			if n != nil {
+				got = append(got, n.Pos().String()+"-"+n.End().String())
+			}
+			return true
+		})
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("wrong spans for %q.\nwant=%v\ngot=%v", input, want, got)
+		}
+
+		again, err := json.Marshal(&decoded)
+		if err != nil {
+			t.Fatalf("json.Marshal of decoded %q failed: %s", input, err)
+		}
+		if string(again) != string(data) {
+			t.Errorf("encoding not stable for %q.\nwant=%s\ngot=%s", input, data, again)
+		}
+	}
+}
+
+func TestMarshalNode(t *testing.T) {
+	program := parse(t, "x + 10")
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+	data, err := ast.MarshalNode(stmt.Expression)
+	if err != nil {
+		t.Fatalf("MarshalNode failed: %s", err)
+	}
+
+	expected := `{"kind":"InfixExpression",` +
+		`"start":{"offset":0,"line":1,"column":1},"end":{"offset":6,"line":1,"column":7},` +
+		`"left":{"kind":"Identifier",` +
+		`"start":{"offset":0,"line":1,"column":1},"end":{"offset":1,"line":1,"column":2},"value":"x"},` +
+		`"operator":"+",` +
+		`"right":{"kind":"IntegerLiteral",` +
+		`"start":{"offset":4,"line":1,"column":5},"end":{"offset":6,"line":1,"column":7},` +
+		`"value":10,"literal":"10"}}`
+	if string(data) != expected {
+		t.Errorf("wrong encoding.\nwant=%s\ngot=%s", expected, data)
+	}
+
+	// Parentheses are part of the span of the expression they are in.
+	program = parse(t, "(x) * 2")
+	stmt = program.Statements[0].(*ast.ExpressionStatement)
+
+	data, err = ast.MarshalNode(stmt.Expression)
+	if err != nil {
+		t.Fatalf("MarshalNode failed: %s", err)
+	}
+
+	expected = `{"kind":"InfixExpression",` +
+		`"start":{"offset":0,"line":1,"column":1},"end":{"offset":7,"line":1,"column":8},` +
+		`"left":{"kind":"ParenExpression",` +
+		`"start":{"offset":0,"line":1,"column":1},"end":{"offset":3,"line":1,"column":4},` +
+		`"expression":{"kind":"Identifier",` +
+		`"start":{"offset":1,"line":1,"column":2},"end":{"offset":2,"line":1,"column":3},"value":"x"}},` +
+		`"operator":"*",` +
+		`"right":{"kind":"IntegerLiteral",` +
+		`"start":{"offset":6,"line":1,"column":7},"end":{"offset":7,"line":1,"column":8},` +
+		`"value":2,"literal":"2"}}`
+	if string(data) != expected {
+		t.Errorf("wrong encoding of a grouped expression.\nwant=%s\ngot=%s", expected, data)
+	}
+
+	// Nodes built without positions have no span.
+	data, err = ast.MarshalNode(&ast.Identifier{Value: "y"})
+	if err != nil {
+		t.Fatalf("MarshalNode failed: %s", err)
+	}
+	if string(data) != `{"kind":"Identifier","value":"y"}` {
+		t.Errorf("wrong encoding of a node without positions: %s", data)
+	}
+}
+
+func TestUnmarshalNodeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`null`, "ast: no node to decode"},
+		{`{"kind":"Loop"}`, "ast: Loop: unknown node kind"},
+		{`{"value":"x"}`, "ast: missing kind"},
+		{`{"kind":"PrefixExpression","operator":"-"}`, "ast: PrefixExpression: missing right"},
+		{`{"kind":"ReturnStatement","returnValue":null}`, "ast: ReturnStatement: returnValue is null"},
+		{`{"kind":"ExpressionStatement","expression":{"kind":"BreakStatement"}}`,
+			"ast: ExpressionStatement: expression: BreakStatement is not an expression"},
+		{`{"kind":"Program","statements":[{"kind":"Identifier","value":"x"}]}`,
+			"ast: Program: statements: Identifier is not a statement"},
+		{`{"kind":"IntegerLiteral","value":1.5}`, "ast: IntegerLiteral: invalid integer 1.5"},
+		{`{"kind":"Identifier","value":1}`,
+			"ast: Identifier: value: json: cannot unmarshal number into Go value of type string"},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.UnmarshalNode([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected an error for %s", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error for %s. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+
+	var program ast.Program
+	err := json.Unmarshal([]byte(`{"kind":"Identifier","value":"x"}`), &program)
+	if err == nil || err.Error() != "ast: Identifier is not a Program" {
+		t.Errorf("wrong error decoding a Program: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package ast
+
+// ModifierFunc returns the node to put in the place of node, or node
+// itself to keep it.
+type ModifierFunc func(node Node) Node
+
+// Modify rewrites the tree rooted at node bottom-up: the children of a node
+// are modified, in place, before the node itself is passed to modifier.
+// Returns what modifier returns for node.
+//
+// A replacement must be of the same kind as the node it replaces: an
+// Expression for an expression, a Statement for a statement and a
+// *BlockStatement for a block. Identifiers that declare a name, like the
+// name of a let statement or the parameters of a function, are not passed
+// to modifier, and neither are the path of an import statement and the name
+// after the dot of a member expression.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch n := node.(type) {
+	case *Program:
+		for i, s := range n.Statements {
+			n.Statements[i] = modifyStatement(s, modifier)
+		}
+
+	case *LetStatement:
+		n.Value = modifyExpression(n.Value, modifier)
+
+	case *ReturnStatement:
+		n.ReturnValue = modifyExpression(n.ReturnValue, modifier)
+
+	case *ExpressionStatement:
+		n.Expression = modifyExpression(n.Expression, modifier)
+
+	case *BlockStatement:
+		for i, s := range n.Statements {
+			n.Statements[i] = modifyStatement(s, modifier)
+		}
+
+	case *WhileStatement:
+		n.Condition = modifyExpression(n.Condition, modifier)
+		n.Body = Modify(n.Body, modifier).(*BlockStatement)
+
+	case *ForStatement:
+		n.Iterable = modifyExpression(n.Iterable, modifier)
+		n.Body = Modify(n.Body, modifier).(*BlockStatement)
+
+	case *PrefixExpression:
+		n.Right = modifyExpression(n.Right, modifier)
+
+	case *InfixExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Right = modifyExpression(n.Right, modifier)
+
+	case *ParenExpression:
+		n.Expression = modifyExpression(n.Expression, modifier)
+
+	case *LogicalExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Right = modifyExpression(n.Right, modifier)
+
+	case *AssignExpression:
+		n.Target = modifyExpression(n.Target, modifier)
+		n.Value = modifyExpression(n.Value, modifier)
+
+	case *IfExpression:
+		n.Condition = modifyExpression(n.Condition, modifier)
+		n.Consequence = Modify(n.Consequence, modifier).(*BlockStatement)
+		if n.Alternative != nil {
+			n.Alternative = Modify(n.Alternative, modifier).(*BlockStatement)
+		}
+
+	case *FunctionLiteral:
+		n.Body = Modify(n.Body, modifier).(*BlockStatement)
+
+	case *MacroLiteral:
+		n.Body = Modify(n.Body, modifier).(*BlockStatement)
+
+	case *CallExpression:
+		n.Function = modifyExpression(n.Function, modifier)
+		for i, a := range n.Arguments {
+			n.Arguments[i] = modifyExpression(a, modifier)
+		}
+
+	case *ArrayLiteral:
+		for i, e := range n.Elements {
+			n.Elements[i] = modifyExpression(e, modifier)
+		}
+
+	case *IndexExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Index = modifyExpression(n.Index, modifier)
+
+	case *MemberExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+
+	case *HashLiteral:
+		for i, pair := range n.Pairs {
+			n.Pairs[i] = HashPair{
+				Key:   modifyExpression(pair.Key, modifier),
+				Value: modifyExpression(pair.Value, modifier),
+			}
+		}
+	}
+
+	return modifier(node)
+}
+
+func modifyStatement(s Statement, modifier ModifierFunc) Statement {
+	if s == nil {
+		return nil
+	}
+	return Modify(s, modifier).(Statement)
+}
+
+func modifyExpression(e Expression, modifier ModifierFunc) Expression {
+	if e == nil {
+		return nil
+	}
+	return Modify(e, modifier).(Expression)
+}
@@ -0,0 +1,63 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/jolisper/monkey/ast"
+)
+
+func TestModify(t *testing.T) {
+	turnOneIntoTwo := func(node ast.Node) ast.Node {
+		integer, ok := node.(*ast.IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+
+		integer.Value = 2
+		integer.Token.Literal = "2"
+		return integer
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1", "2"},
+		{"1 + 1", "(2 + 2)"},
+		{"-1 && [1, 3][1]", "((-2) && ([2, 3][2]))"},
+		{"let a = 1; return 1;", "let a = 2;return 2;"},
+		{"if (1) { 1 } else { 1 }", "if2 2else 2"},
+		{"fn(x) { 1 }(1)", "fn(x) 2(2)"},
+		{`{1: 1}`, "{2: 2}"},
+		{"while (1) { a = 1 }", "while2 a = 2"},
+		{"for (x in [1]) { 1 }", "for (x in [2]) 2"},
+	}
+
+	for _, tt := range tests {
+		modified := ast.Modify(parse(t, tt.input), turnOneIntoTwo)
+
+		if modified.String() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q", tt.input, tt.expected, modified.String())
+		}
+	}
+}
+
+func TestModifyReplacesNodes(t *testing.T) {
+	program := parse(t, "f(x); x")
+
+	inlineX := func(node ast.Node) ast.Node {
+		if ident, ok := node.(*ast.Identifier); ok && ident.Value == "x" {
+			return parse(t, "(a + b)").Statements[0].(*ast.ExpressionStatement).Expression
+		}
+		if _, ok := node.(*ast.ExpressionStatement); ok && node.String() == "(a + b)" {
+			return parse(t, "let y = 0;").Statements[0]
+		}
+		return node
+	}
+
+	modified := ast.Modify(program, inlineX)
+
+	if modified.String() != "f((a + b))let y = 0;" {
+		t.Errorf("wrong result. got=%q", modified.String())
+	}
+}
@@ -0,0 +1,136 @@
+package ast_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jolisper/monkey/ast"
+	"github.com/jolisper/monkey/lexer"
+	"github.com/jolisper/monkey/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = -1;", []string{"Program", "LetStatement", "Identifier a", "PrefixExpression", "IntegerLiteral 1"}},
+		{"return a && b;", []string{"Program", "ReturnStatement", "LogicalExpression", "Identifier a", "Identifier b"}},
+		{"if (a) { 1 } else { 2.5 }", []string{
+			"Program", "ExpressionStatement", "IfExpression", "Identifier a",
+			"BlockStatement", "ExpressionStatement", "IntegerLiteral 1",
+			"BlockStatement", "ExpressionStatement", "FloatLiteral 2.5",
+		}},
+		{"fn(x, y) { x + y }", []string{
+			"Program", "ExpressionStatement", "FunctionLiteral", "Identifier x", "Identifier y",
+			"BlockStatement", "ExpressionStatement", "InfixExpression", "Identifier x", "Identifier y",
+		}},
+		{`f("s", [true])[0]`, []string{
+			"Program", "ExpressionStatement", "IndexExpression", "CallExpression", "Identifier f",
+			"StringLiteral s", "ArrayLiteral", "Boolean true", "IntegerLiteral 0",
+		}},
+		{`{"k": v}`, []string{"Program", "ExpressionStatement", "HashLiteral", "StringLiteral k", "Identifier v"}},
+		{"while (c) { a += 1; break; }", []string{
+			"Program", "WhileStatement", "Identifier c", "BlockStatement",
+			"ExpressionStatement", "AssignExpression", "Identifier a", "IntegerLiteral 1", "BreakStatement",
+		}},
+		{"for (x in xs) { continue; }", []string{
+			"Program", "ForStatement", "Identifier x", "Identifier xs", "BlockStatement", "ContinueStatement",
+		}},
+	}
+
+	for _, tt := range tests {
+		var visited []string
+		ast.Inspect(parse(t, tt.input), func(node ast.Node) bool {
+			if node != nil {
+				visited = append(visited, describe(node))
+			}
+			return true
+		})
+
+		if strings.Join(visited, ", ") != strings.Join(tt.expected, ", ") {
+			t.Errorf("wrong nodes visited for %q.\nwant=%v\ngot= %v", tt.input, tt.expected, visited)
+		}
+	}
+}
+
+func TestInspectPruning(t *testing.T) {
+	program := parse(t, "let f = fn() { inner }; outer")
+
+	var identifiers []string
+	ends := 0
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case nil:
+			ends++
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.Identifier:
+			identifiers = append(identifiers, node.Value)
+		}
+		return true
+	})
+
+	if strings.Join(identifiers, " ") != "f outer" {
+		t.Errorf("wrong identifiers visited. got=%v", identifiers)
+	}
+	// one end for each node visited with its children: Program,
+	// LetStatement, ExpressionStatement and the two identifiers
+	if ends != 5 {
+		t.Errorf("wrong number of nil visits. want=5, got=%d", ends)
+	}
+}
+
+type counter struct {
+	depth, maxDepth int
+}
+
+func (c *counter) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		c.depth--
+		return nil
+	}
+	c.depth++
+	if c.depth > c.maxDepth {
+		c.maxDepth = c.depth
+	}
+	return c
+}
+
+func TestWalk(t *testing.T) {
+	c := &counter{}
+	ast.Walk(c, parse(t, "fn() { if (a) { [1] } }"))
+
+	// Program, ExpressionStatement, FunctionLiteral, BlockStatement,
+	// ExpressionStatement, IfExpression, BlockStatement,
+	// ExpressionStatement, ArrayLiteral, IntegerLiteral
+	if c.maxDepth != 10 {
+		t.Errorf("wrong depth. want=10, got=%d", c.maxDepth)
+	}
+	if c.depth != 0 {
+		t.Errorf("unbalanced nil visits. depth=%d", c.depth)
+	}
+}
+
+func describe(node ast.Node) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	switch node := node.(type) {
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.FloatLiteral, *ast.Boolean:
+		return name + " " + node.String()
+	case *ast.StringLiteral:
+		return name + " " + node.Value
+	}
+	return name
+}
@@ -0,0 +1,887 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jolisper/monkey/ast"
+	"github.com/jolisper/monkey/code"
+	"github.com/jolisper/monkey/object"
+)
+
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// CompilationScope holds the instructions emitted for one function body, or
+// for the main program.
+type CompilationScope struct {
+	instructions        code.Instructions
+	positions           code.Positions
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+
+	// Loops being compiled, innermost last
+	loops []*loopJumps
+
+	// Values pushed by the expressions being compiled that are still waiting
+	// on the stack for their operator, see compilePending
+	pending int
+}
+
+// loopJumps collects the positions of the jumps emitted for the break and
+// continue statements of a loop, patched once the loop is compiled.
+type loopJumps struct {
+	breaks    []int
+	continues []int
+
+	// Values pending when the loop started, which its jumps must leave
+	pending int
+}
+
+type Compiler struct {
+	constants []object.Object
+
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	// The node being compiled, that the emitted instructions come from
+	node ast.Node
+
+	// The first operand found too large for its instruction, see emit
+	err error
+}
+
+// Bytecode is the result of a compilation: the instructions of the main
+// program, their source positions and the constant pool they refer to.
+type Bytecode struct {
+	Instructions code.Instructions
+	Positions    code.Positions
+	Constants    []object.Object
+}
+
+var binaryOperators = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"**": code.OpPow,
+	"&":  code.OpBitAnd,
+	"|":  code.OpBitOr,
+	"^":  code.OpBitXor,
+	"<<": code.OpShiftLeft,
+	">>": code.OpShiftRight,
+	">":  code.OpGreaterThan,
+	">=": code.OpGreaterEqual,
+	"<":  code.OpLessThan,
+	"<=": code.OpLessEqual,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+}
+
+func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	symbolTable := NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	return &Compiler{
+		constants:   []object.Object{},
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+	}
+}
+
+// NewWithState returns a compiler that keeps defining globals and constants
+// on top of the given ones, so that successive inputs, as in the REPL, can
+// refer to each other.
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
+}
+
+// Compile compiles node, adding its instructions to the current scope. The
+// operands of the instructions have a fixed width: a program with more
+// constants, variables, arguments or code than they can address is an error.
+func (c *Compiler) Compile(node ast.Node) error {
+	outer := c.node
+	c.node = node
+	err := c.compile(node)
+	c.node = outer
+
+	if err == nil {
+		err = c.err
+	}
+	return err
+}
+
+func (c *Compiler) compile(node ast.Node) error {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.LetStatement:
+		if c.symbolTable.DefinesConst(node.Name.Value) {
+			return c.errorf(node.Name, "redeclaration of constant: %s", node.Name.Value)
+		}
+
+		// A function refers to the name it is bound to like to any other
+		// variable, which makes recursion possible: the name is defined
+		// before the function is compiled, to be captured by it
+		var symbol Symbol
+		_, isFunction := ast.Unparen(node.Value).(*ast.FunctionLiteral)
+		if isFunction {
+			symbol = c.defineLet(node)
+		}
+
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+
+		if !isFunction {
+			symbol = c.defineLet(node)
+		}
+		c.storeSymbol(symbol)
+
+	case *ast.ReturnStatement:
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.WhileStatement:
+		return c.compileWhileStatement(node)
+
+	case *ast.ForStatement:
+		return c.compileForStatement(node)
+
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return c.errorf(node, "break outside loop")
+		}
+		loop.breaks = append(loop.breaks, c.emitLoopJump(loop))
+
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return c.errorf(node, "continue outside loop")
+		}
+		loop.continues = append(loop.continues, c.emitLoopJump(loop))
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			return c.errorf(node, "identifier not found: %s", node.Value)
+		}
+		c.loadSymbol(symbol)
+
+	case *ast.IntegerLiteral:
+		var integer object.Object = &object.Integer{Value: node.Value}
+		if node.Big != nil {
+			integer = object.NewInteger(node.Big)
+		}
+		c.emit(code.OpConstant, c.addConstant(integer))
+
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		case "~":
+			c.emit(code.OpBitNot)
+		default:
+			return c.errorf(node, "unknown operator %s", node.Operator)
+		}
+
+	case *ast.InfixExpression:
+		op, ok := binaryOperators[node.Operator]
+		if !ok {
+			return c.errorf(node, "unknown operator %s", node.Operator)
+		}
+
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.compilePending(node.Right, 1); err != nil {
+			return err
+		}
+		c.emit(op)
+
+	case *ast.ParenExpression:
+		return c.Compile(node.Expression)
+
+	case *ast.LogicalExpression:
+		return c.compileLogicalExpression(node)
+
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
+
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+
+	case *ast.ArrayLiteral:
+		for i, el := range node.Elements {
+			if err := c.compilePending(el, i); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		for i, pair := range node.Pairs {
+			if err := c.compilePending(pair.Key, 2*i); err != nil {
+				return err
+			}
+			if err := c.compilePending(pair.Value, 2*i+1); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpHash, len(node.Pairs)*2)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.compilePending(node.Index, 1); err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+
+	case *ast.FunctionLiteral:
+		return c.compileFunction(node)
+
+	case *ast.MacroLiteral:
+		return c.errorf(node, "macros must be defined by a top-level let")
+
+	case *ast.ImportStatement:
+		return c.errorf(node, "import is only supported by the evaluator")
+
+	case *ast.MemberExpression:
+		return c.errorf(node, "member access is only supported by the evaluator")
+
+	case *ast.CallExpression:
+		if ident, ok := node.Function.(*ast.Identifier); ok && ident.Value == "quote" {
+			return c.errorf(node, "quote is only supported by the evaluator")
+		}
+
+		if err := c.Compile(node.Function); err != nil {
+			return err
+		}
+
+		for i, a := range node.Arguments {
+			if err := c.compilePending(a, i+1); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpCall, len(node.Arguments))
+
+	default:
+		return c.errorf(node, "unsupported node %T", node)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	// Emit an `OpJumpNotTruthy` with a bogus value, patched below
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileBlockValue(node.Consequence); err != nil {
+		return err
+	}
+
+	// Emit an `OpJump` with a bogus value, patched below
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	afterConsequencePos := len(c.currentInstructions())
+	c.changeOperand(jumpNotTruthyPos, afterConsequencePos)
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else if err := c.compileBlockValue(node.Alternative); err != nil {
+		return err
+	}
+
+	afterAlternativePos := len(c.currentInstructions())
+	c.changeOperand(jumpPos, afterAlternativePos)
+
+	return nil
+}
+
+// Compiles an assignment, leaving the assigned value on the stack. Captured
+// variables live in cells shared with the enclosing function, so they can be
+// assigned from either side.
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	compound := node.Operator != "="
+	operator, ok := binaryOperators[strings.TrimSuffix(node.Operator, "=")]
+	if compound && !ok {
+		return c.errorf(node, "unknown operator %s", node.Operator)
+	}
+
+	switch target := ast.Unparen(node.Target).(type) {
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(target.Value)
+		if !ok || symbol.Scope == BuiltinScope {
+			return c.errorf(target, "assignment to undeclared variable: %s", target.Value)
+		}
+		if symbol.Constant {
+			return c.errorf(target, "assignment to constant: %s", target.Value)
+		}
+
+		pending := 0
+		if compound {
+			c.loadSymbol(symbol)
+			pending = 1
+		}
+		if err := c.compilePending(node.Value, pending); err != nil {
+			return err
+		}
+		if compound {
+			c.emit(operator)
+		}
+
+		c.storeSymbol(symbol)
+		c.loadSymbol(symbol)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(target.Left); err != nil {
+			return err
+		}
+		if err := c.compilePending(target.Index, 1); err != nil {
+			return err
+		}
+		if err := c.compilePending(node.Value, 2); err != nil {
+			return err
+		}
+		if compound {
+			c.emitAt(target, code.OpSetIndexCompound, int(operator))
+		} else {
+			c.emitAt(target, code.OpSetIndex)
+		}
+
+	default:
+		return c.errorf(node, "cannot assign to %s", node.Target.String())
+	}
+
+	return nil
+}
+
+// Compiles `&&` and `||` into conditional jumps so that the right operand is
+// skipped when the left one decides the result. Both operators leave a
+// boolean on the stack.
+func (c *Compiler) compileLogicalExpression(node *ast.LogicalExpression) error {
+	if node.Operator != "&&" && node.Operator != "||" {
+		return c.errorf(node, "unknown operator %s", node.Operator)
+	}
+
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+
+	if node.Operator == "&&" {
+		// left falsy -> false, otherwise the truthiness of right
+		jumpToFalse := c.emit(code.OpJumpNotTruthy, 9999)
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		jumpToFalseRight := c.emit(code.OpJumpNotTruthy, 9999)
+		c.emit(code.OpTrue)
+		jumpToEnd := c.emit(code.OpJump, 9999)
+
+		falsePos := len(c.currentInstructions())
+		c.changeOperand(jumpToFalse, falsePos)
+		c.changeOperand(jumpToFalseRight, falsePos)
+		c.emit(code.OpFalse)
+
+		c.changeOperand(jumpToEnd, len(c.currentInstructions()))
+		return nil
+	}
+
+	// left truthy -> true, otherwise the truthiness of right
+	jumpToRight := c.emit(code.OpJumpNotTruthy, 9999)
+	c.emit(code.OpTrue)
+	jumpToEnd := c.emit(code.OpJump, 9999)
+
+	c.changeOperand(jumpToRight, len(c.currentInstructions()))
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+	jumpToFalse := c.emit(code.OpJumpNotTruthy, 9999)
+	c.emit(code.OpTrue)
+	jumpToEndRight := c.emit(code.OpJump, 9999)
+
+	c.changeOperand(jumpToFalse, len(c.currentInstructions()))
+	c.emit(code.OpFalse)
+
+	endPos := len(c.currentInstructions())
+	c.changeOperand(jumpToEnd, endPos)
+	c.changeOperand(jumpToEndRight, endPos)
+	return nil
+}
+
+func (c *Compiler) compileWhileStatement(node *ast.WhileStatement) error {
+	startPos := len(c.currentInstructions())
+
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	// Emit an `OpJumpNotTruthy` with a bogus value, patched below
+	exitJumpPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileLoopBody(node.Body, startPos); err != nil {
+		return err
+	}
+
+	exitPos := len(c.currentInstructions())
+	c.changeOperand(exitJumpPos, exitPos)
+	c.patchLoopJumps(exitPos, startPos)
+
+	return nil
+}
+
+// Compiles a for loop. The iterator is kept in a hidden variable, named so
+// that it cannot clash with an identifier, rather than on the stack, so
+// that break and continue can jump out of the body without cleaning up.
+func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
+	if c.symbolTable.DefinesConst(node.Variable.Value) {
+		return c.errorf(node.Variable, "redeclaration of constant: %s", node.Variable.Value)
+	}
+
+	if err := c.Compile(node.Iterable); err != nil {
+		return err
+	}
+	c.emitAt(node.Iterable, code.OpIter)
+
+	iterator := c.symbolTable.Define(fmt.Sprintf("for@%d", node.Pos().Offset))
+	c.storeSymbol(iterator)
+
+	startPos := len(c.currentInstructions())
+	c.loadSymbol(iterator)
+
+	// Emit an `OpIterNext` with a bogus value, patched below
+	exitJumpPos := c.emit(code.OpIterNext, 9999)
+	c.storeSymbol(c.symbolTable.Define(node.Variable.Value))
+
+	if err := c.compileLoopBody(node.Body, startPos); err != nil {
+		return err
+	}
+
+	exitPos := len(c.currentInstructions())
+	c.changeOperand(exitJumpPos, exitPos)
+	c.patchLoopJumps(exitPos, startPos)
+
+	return nil
+}
+
+// Compiles the body of a loop followed by the jump back to startPos. The
+// break and continue statements in the body are left to patchLoopJumps.
+func (c *Compiler) compileLoopBody(body *ast.BlockStatement, startPos int) error {
+	scope := &c.scopes[c.scopeIndex]
+	scope.loops = append(scope.loops, &loopJumps{pending: scope.pending})
+
+	if err := c.Compile(body); err != nil {
+		return err
+	}
+	c.emit(code.OpJump, startPos)
+
+	return nil
+}
+
+// Points the jumps of the innermost loop's break and continue statements to
+// exitPos and continuePos, and leaves the loop.
+func (c *Compiler) patchLoopJumps(exitPos, continuePos int) {
+	scope := &c.scopes[c.scopeIndex]
+	loop := scope.loops[len(scope.loops)-1]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+
+	for _, pos := range loop.breaks {
+		c.changeOperand(pos, exitPos)
+	}
+	for _, pos := range loop.continues {
+		c.changeOperand(pos, continuePos)
+	}
+}
+
+// Emits the jump of a break or continue statement to be patched by
+// patchLoopJumps, first popping the values that the expressions around the
+// statement left on the stack since the loop started.
+func (c *Compiler) emitLoopJump(loop *loopJumps) int {
+	for i := loop.pending; i < c.scopes[c.scopeIndex].pending; i++ {
+		c.emit(code.OpPop)
+	}
+	return c.emit(code.OpJump, 9999)
+}
+
+// Compiles node while n values pushed by the enclosing expression wait on
+// the stack, e.g. the left operand while compiling the right one. A break
+// or continue in node, inside an if expression, must pop them.
+func (c *Compiler) compilePending(node ast.Node, n int) error {
+	c.scopes[c.scopeIndex].pending += n
+	err := c.Compile(node)
+	c.scopes[c.scopeIndex].pending -= n
+	return err
+}
+
+// Returns the innermost loop of the current function, or nil outside loops.
+func (c *Compiler) currentLoop() *loopJumps {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
+}
+
+// Compiles a block used as an expression, leaving its value on the stack.
+// Blocks not ending in an expression statement evaluate to null.
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+// Compiles a function literal into a closure.
+func (c *Compiler) compileFunction(node *ast.FunctionLiteral) error {
+	c.enterScope()
+
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.numDefinitions
+	positions := c.scopes[c.scopeIndex].positions
+	instructions := c.leaveScope()
+
+	for _, s := range freeSymbols {
+		c.captureSymbol(s)
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Instructions:  instructions,
+		Positions:     positions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+	}
+
+	fnIndex := c.addConstant(compiledFn)
+	c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+
+	return nil
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Positions:    c.scopes[c.scopeIndex].positions,
+		Constants:    c.constants,
+	}
+}
+
+// SymbolTable returns the global symbol table, to be handed to
+// NewWithState.
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// Describes what a program has too many of when an operand of the opcode
+// does not fit in its width, by operand.
+var operandLimits = map[code.Opcode][]string{
+	code.OpConstant:      {"constants"},
+	code.OpJumpNotTruthy: {"instructions to jump over"},
+	code.OpJump:          {"instructions to jump over"},
+	code.OpIterNext:      {"instructions to jump over"},
+	code.OpGetGlobal:     {"global variables"},
+	code.OpSetGlobal:     {"global variables"},
+	code.OpGetLocal:      {"local variables"},
+	code.OpSetLocal:      {"local variables"},
+	code.OpCaptureLocal:  {"local variables"},
+	code.OpGetFree:       {"free variables"},
+	code.OpSetFree:       {"free variables"},
+	code.OpCaptureFree:   {"free variables"},
+	code.OpArray:         {"array elements"},
+	code.OpHash:          {"hash pairs"},
+	code.OpCall:          {"arguments"},
+	code.OpClosure:       {"constants", "free variables"},
+}
+
+// Reports an error at the node being compiled, unless one was already
+// found, for the first operand of op that does not fit in its width. The
+// instruction would silently refer to something else otherwise.
+func (c *Compiler) checkOperands(op code.Opcode, operands []int) {
+	def, err := code.Lookup(byte(op))
+	if err != nil || c.err != nil {
+		return
+	}
+
+	for i, operand := range operands {
+		if operand < 1<<(8*def.OperandWidths[i]) {
+			continue
+		}
+
+		if limits, ok := operandLimits[op]; ok {
+			c.err = c.errorf(c.node, "too many %s", limits[i])
+		} else {
+			c.err = c.errorf(c.node, "operand %d of %s out of range", operand, def.Name)
+		}
+		return
+	}
+}
+
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	c.checkOperands(op, operands)
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+	c.addPosition(pos)
+
+	return pos
+}
+
+// Emits an instruction located at node, instead of the node being compiled,
+// for the runtime errors it raises to point where the evaluator's do.
+func (c *Compiler) emitAt(node ast.Node, op code.Opcode, operands ...int) int {
+	outer := c.node
+	c.node = node
+	pos := c.emit(op, operands...)
+	c.node = outer
+	return pos
+}
+
+// Records the position of the node being compiled for the instruction at
+// pos, unless the instructions before it already have the same.
+func (c *Compiler) addPosition(pos int) {
+	if c.node == nil {
+		return
+	}
+
+	scope := &c.scopes[c.scopeIndex]
+	if n := len(scope.positions); n > 0 && scope.positions[n-1].Pos == c.node.Pos() {
+		return
+	}
+	scope.positions = append(scope.positions, code.PositionEntry{Offset: pos, Pos: c.node.Pos()})
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	updatedInstructions := append(c.currentInstructions(), ins...)
+
+	c.scopes[c.scopeIndex].instructions = updatedInstructions
+
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	new := old[:last.Position]
+
+	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].lastInstruction = previous
+
+	positions := c.scopes[c.scopeIndex].positions
+	for len(positions) > 0 && positions[len(positions)-1].Offset >= last.Position {
+		positions = positions[:len(positions)-1]
+	}
+	c.scopes[c.scopeIndex].positions = positions
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	c.checkOperands(op, []int{operand})
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+// Defines the variable declared by a let statement.
+func (c *Compiler) defineLet(node *ast.LetStatement) Symbol {
+	if node.IsConst() {
+		return c.symbolTable.DefineConst(node.Name.Value)
+	}
+	return c.symbolTable.Define(node.Name.Value)
+}
+
+// Emits the instruction popping the top of the stack into the variable s.
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpSetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+// Emits the instruction pushing the free variable s of the function being
+// compiled, to be captured by its OpClosure: the cell holding a variable
+// of the enclosing function, so that both share it.
+func (c *Compiler) captureSymbol(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpCaptureLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpCaptureFree, s.Index)
+	}
+}
+
+// Compile errors are prefixed with the position of the offending node, the
+// same way parser errors are.
+func (c *Compiler) errorf(node ast.Node, format string, a ...interface{}) error {
+	return fmt.Errorf("%s: %s", node.Pos(), fmt.Sprintf(format, a...))
+}
@@ -0,0 +1,114 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope  SymbolScope = "GLOBAL"
+	LocalScope   SymbolScope = "LOCAL"
+	BuiltinScope SymbolScope = "BUILTIN"
+	FreeScope    SymbolScope = "FREE"
+)
+
+type Symbol struct {
+	Name     string
+	Scope    SymbolScope
+	Index    int
+	Constant bool
+}
+
+// SymbolTable resolves identifiers to the slot they are stored in. Each
+// function body gets its own table enclosed by the table of the code that
+// defines it.
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store          map[string]Symbol
+	numDefinitions int
+
+	FreeSymbols []Symbol
+}
+
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	free := []Symbol{}
+	return &SymbolTable{store: s, FreeSymbols: free}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define binds name to a new slot of the table. Redefining a name already
+// stored in the table reuses its slot, so that code compiled before the
+// redefinition, like the condition of a loop, sees the new value.
+func (s *SymbolTable) Define(name string) Symbol {
+	return s.define(name, false)
+}
+
+// DefineConst is like Define, marking the symbol as a constant.
+func (s *SymbolTable) DefineConst(name string) Symbol {
+	return s.define(name, true)
+}
+
+// DefinesConst reports whether name is defined as a constant in this table
+// itself, not in an outer one.
+func (s *SymbolTable) DefinesConst(name string) bool {
+	return s.store[name].Constant
+}
+
+func (s *SymbolTable) define(name string, constant bool) Symbol {
+	if existing, ok := s.store[name]; ok {
+		if existing.Scope == GlobalScope || existing.Scope == LocalScope {
+			existing.Constant = constant
+			s.store[name] = existing
+			return existing
+		}
+	}
+
+	symbol := Symbol{Name: name, Index: s.numDefinitions, Constant: constant}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	obj, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		obj, ok = s.Outer.Resolve(name)
+		if !ok {
+			return obj, ok
+		}
+
+		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+			return obj, ok
+		}
+
+		free := s.defineFree(obj)
+		return free, true
+	}
+	return obj, ok
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Constant: original.Constant}
+	symbol.Scope = FreeScope
+
+	s.store[original.Name] = symbol
+	return symbol
+}
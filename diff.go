@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lines of context around the changes of a diff
+const diffContext = 3
+
+type diffLine struct {
+	kind byte // ' ' for a common line, '-' for a removed one, '+' for an added one
+	text string
+	a, b int // number of lines of the old and new text before this line
+}
+
+// unifiedDiff returns the changes from old to new in the unified format of
+// diff -u, or "" when there are none.
+func unifiedDiff(name, old, new string) string {
+	if old == new {
+		return ""
+	}
+
+	lines := diffLines(splitLines(old), splitLines(new))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s.orig\n+++ %s\n", name, name)
+
+	for i := 0; i < len(lines); {
+		if lines[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Changes closer than twice the context go in the same hunk
+		lastChange := i
+		for j := i; j < len(lines) && j-lastChange <= 2*diffContext; j++ {
+			if lines[j].kind != ' ' {
+				lastChange = j
+			}
+		}
+
+		start := maxInt(i-diffContext, 0)
+		end := minInt(lastChange+diffContext+1, len(lines))
+		writeHunk(&out, lines[start:end])
+		i = end
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, hunk []diffLine) {
+	oldCount, newCount := 0, 0
+	for _, line := range hunk {
+		if line.kind != '+' {
+			oldCount++
+		}
+		if line.kind != '-' {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n",
+		hunkRange(hunk[0].a, oldCount), hunkRange(hunk[0].b, newCount))
+
+	for _, line := range hunk {
+		out.WriteByte(line.kind)
+		out.WriteString(line.text)
+		if !strings.HasSuffix(line.text, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// Formats the range of a hunk starting after line before, like diff does:
+// an empty range names the line before it and a count of 1 is left out.
+func hunkRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// Splits s into lines, each keeping its newline but maybe the last one.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Computes the shortest edit from the lines x to the lines y, based on
+// their longest common subsequence. The lines common to the start and the
+// end of both are skipped first, the rest goes to Hirschberg's algorithm,
+// which needs memory linear in the number of lines.
+func diffLines(x, y []string) []diffLine {
+	n, m := len(x), len(y)
+
+	prefix := 0
+	for prefix < n && prefix < m && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < n-prefix && suffix < m-prefix && x[n-1-suffix] == y[m-1-suffix] {
+		suffix++
+	}
+
+	var lines []diffLine
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, diffLine{' ', x[i], i, i})
+	}
+	lines = diffRange(lines, x, y, prefix, n-suffix, prefix, m-suffix)
+	for i, j := n-suffix, m-suffix; i < n; i, j = i+1, j+1 {
+		lines = append(lines, diffLine{' ', x[i], i, j})
+	}
+
+	return lines
+}
+
+// Appends to lines the edit from x[x0:x1] to y[y0:y1]. The longest common
+// subsequence goes through the middle line of x at the split of y that the
+// lengths computed from both ends agree on, and each half is diffed apart.
+func diffRange(lines []diffLine, x, y []string, x0, x1, y0, y1 int) []diffLine {
+	switch {
+	case x0 == x1:
+		for j := y0; j < y1; j++ {
+			lines = append(lines, diffLine{'+', y[j], x0, j})
+		}
+		return lines
+
+	case y0 == y1:
+		for i := x0; i < x1; i++ {
+			lines = append(lines, diffLine{'-', x[i], i, y0})
+		}
+		return lines
+
+	case x1-x0 == 1:
+		for j := y0; j < y1; j++ {
+			if x[x0] == y[j] {
+				lines = diffRange(lines, x, y, x0, x0, y0, j)
+				lines = append(lines, diffLine{' ', x[x0], x0, j})
+				return diffRange(lines, x, y, x1, x1, j+1, y1)
+			}
+		}
+		lines = append(lines, diffLine{'-', x[x0], x0, y0})
+		return diffRange(lines, x, y, x1, x1, y0, y1)
+	}
+
+	mid := (x0 + x1) / 2
+	forward := lcsLengths(x[x0:mid], y[y0:y1], false)
+	backward := lcsLengths(x[mid:x1], y[y0:y1], true)
+
+	split := 0
+	for j := range forward {
+		if forward[j]+backward[j] > forward[split]+backward[split] {
+			split = j
+		}
+	}
+
+	lines = diffRange(lines, x, y, x0, mid, y0, y0+split)
+	return diffRange(lines, x, y, mid, x1, y0+split, y1)
+}
+
+// Returns, for each j, the length of the longest common subsequence of x
+// and y[:j], or of x and y[j:] when reverse is set, keeping a single row of
+// the usual table.
+func lcsLengths(x, y []string, reverse bool) []int {
+	m := len(y)
+	row := make([]int, m+1)
+
+	for k := range x {
+		if !reverse {
+			line, diagonal := x[k], 0
+			for j := 1; j <= m; j++ {
+				above := row[j]
+				if line == y[j-1] {
+					row[j] = diagonal + 1
+				} else {
+					row[j] = maxInt(row[j], row[j-1])
+				}
+				diagonal = above
+			}
+		} else {
+			line, diagonal := x[len(x)-1-k], 0
+			for j := m - 1; j >= 0; j-- {
+				below := row[j]
+				if line == y[j] {
+					row[j] = diagonal + 1
+				} else {
+					row[j] = maxInt(row[j], row[j+1])
+				}
+				diagonal = below
+			}
+		}
+	}
+
+	return row
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
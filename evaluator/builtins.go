@@ -0,0 +1,20 @@
+package evaluator
+
+import (
+	"github.com/jolisper/monkey/object"
+)
+
+// RegisterBuiltin makes fn available to Monkey programs under name. Bindings
+// in the environment take precedence over builtins, and registering an
+// existing name replaces the previous builtin. It is meant to be called
+// during program initialization, before any evaluation starts. A builtin
+// returning nil evaluates to NULL.
+func RegisterBuiltin(name string, fn object.BuiltinFunction) {
+	object.RegisterBuiltin(name, fn)
+}
+
+// NewError returns an error object with a formatted message, for use by
+// builtins registered from outside this package.
+func NewError(format string, a ...interface{}) *object.Error {
+	return object.NewError(format, a...)
+}
@@ -2,6 +2,9 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 
 	"github.com/jolisper/monkey/ast"
 	"github.com/jolisper/monkey/object"
@@ -11,41 +14,74 @@ var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
-func Eval(node ast.Node, env *object.Environment) object.Object {
+// Eval evaluates node in env. A Go panic raised while evaluating, e.g. by a
+// builtin, is turned into an error object instead of crashing the host.
+func Eval(node ast.Node, env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = newError("internal error: %v", r)
+		}
+	}()
+
+	return eval(node, env)
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch typedNode := node.(type) {
 	case *ast.Program:
 		return evalProgram(typedNode, env)
 
 	case *ast.ExpressionStatement:
-		return Eval(typedNode.Expression, env)
+		return eval(typedNode.Expression, env)
 
 	case *ast.IntegerLiteral:
+		if typedNode.Big != nil {
+			return object.NewInteger(typedNode.Big)
+		}
 		return &object.Integer{Value: typedNode.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: typedNode.Value}
+
+	case *ast.StringLiteral:
+		return &object.String{Value: typedNode.Value}
+
 	case *ast.Boolean:
 		return nativeBooleanToBooleanObject(typedNode.Value)
 
 	case *ast.PrefixExpression:
-		right := Eval(typedNode.Right, env)
-		if isError(right) {
+		right := eval(typedNode.Right, env)
+		if isAbrupt(right) {
 			return right
 		}
-		return evalPrefixExpression(typedNode.Operator, right)
+		return locateError(evalPrefixExpression(typedNode.Operator, right), typedNode)
 
 	case *ast.InfixExpression:
-		left := Eval(typedNode.Left, env)
-		if isError(left) {
+		left := eval(typedNode.Left, env)
+		if isAbrupt(left) {
 			return left
 		}
 
-		right := Eval(typedNode.Right, env)
-		if isError(right) {
+		right := eval(typedNode.Right, env)
+		if isAbrupt(right) {
 			return right
 		}
 
-		return evalInfixExpression(typedNode.Operator, left, right, env)
+		return locateError(evalInfixExpression(typedNode.Operator, left, right, env), typedNode)
+
+	case *ast.ParenExpression:
+		return eval(typedNode.Expression, env)
+
+	case *ast.LogicalExpression:
+		return evalLogicalExpression(typedNode, env)
+
+	case *ast.AssignExpression:
+		return evalAssignExpression(typedNode, env)
 
 	case *ast.BlockStatement:
 		return evalBlockStatement(typedNode, env)
@@ -54,22 +90,101 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalIfExpression(typedNode, env)
 
 	case *ast.ReturnStatement:
-		val := Eval(typedNode.ReturnValue, env)
-		if isError(val) {
+		val := eval(typedNode.ReturnValue, env)
+		if isAbrupt(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 
 	case *ast.LetStatement:
-		val := Eval(typedNode.Value, env)
-		if isError(val) {
+		if env.Redeclares(typedNode.Name.Value, typedNode) {
+			return locateError(newError("redeclaration of constant: %s", typedNode.Name.Value), typedNode.Name)
+		}
+
+		val := eval(typedNode.Value, env)
+		if isAbrupt(val) {
 			return val
 		}
 
-		env.Set(typedNode.Name.Value, val)
+		if typedNode.IsConst() {
+			env.SetConst(typedNode.Name.Value, val, typedNode)
+		} else {
+			env.Set(typedNode.Name.Value, val)
+		}
+
+	case *ast.ImportStatement:
+		return evalImportStatement(typedNode, env)
+
+	case *ast.WhileStatement:
+		return evalWhileStatement(typedNode, env)
+
+	case *ast.ForStatement:
+		return evalForStatement(typedNode, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
 
 	case *ast.Identifier:
-		return evalIdentifier(typedNode, env)
+		return locateError(evalIdentifier(typedNode, env), typedNode)
+
+	case *ast.FunctionLiteral:
+		params := typedNode.Parameters
+		body := typedNode.Body
+		return &object.Function{Parameters: params, Env: env, Body: body}
+
+	case *ast.MacroLiteral:
+		return locateError(newError("macros must be defined by a top-level let"), typedNode)
+
+	case *ast.CallExpression:
+		if isQuoteCall(typedNode) {
+			return evalQuote(typedNode, env)
+		}
+
+		function := eval(typedNode.Function, env)
+		if isAbrupt(function) {
+			return function
+		}
+
+		args := evalExpressions(typedNode.Arguments, env)
+		if len(args) == 1 && isAbrupt(args[0]) {
+			return args[0]
+		}
+
+		return locateError(applyFunction(function, args), typedNode)
+
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(typedNode.Elements, env)
+		if len(elements) == 1 && isAbrupt(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+
+	case *ast.IndexExpression:
+		left := eval(typedNode.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+
+		index := eval(typedNode.Index, env)
+		if isAbrupt(index) {
+			return index
+		}
+
+		return locateError(evalIndexExpression(left, index), typedNode)
+
+	case *ast.MemberExpression:
+		left := eval(typedNode.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+
+		return locateError(evalMemberExpression(left, typedNode.Member.Value), typedNode.Member)
+
+	case *ast.HashLiteral:
+		return evalHashLiteral(typedNode, env)
 	}
 
 	return nil
@@ -79,7 +194,7 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		result = eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -96,11 +211,11 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		result = eval(statement, env)
 
 		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
 				return result
 			}
 		}
@@ -109,6 +224,71 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+// Loops evaluate to nothing, like let statements. The body runs in the
+// environment of the loop, so a let in the body is seen by the condition.
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := eval(ws.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		if result, done := evalLoopBody(ws.Body, env); done {
+			return result
+		}
+	}
+}
+
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	if env.Redeclares(fs.Variable.Value, fs) {
+		return locateError(newError("redeclaration of constant: %s", fs.Variable.Value), fs.Variable)
+	}
+
+	iterable := eval(fs.Iterable, env)
+	if isAbrupt(iterable) {
+		return iterable
+	}
+
+	iterator, ok := object.NewIterator(iterable)
+	if !ok {
+		return locateError(newError("not iterable: %s", iterable.Type()), fs.Iterable)
+	}
+
+	for {
+		item, ok := iterator.Next()
+		if !ok {
+			return nil
+		}
+
+		env.Set(fs.Variable.Value, item)
+
+		if result, done := evalLoopBody(fs.Body, env); done {
+			return result
+		}
+	}
+}
+
+// Runs one iteration of a loop. It reports whether the loop must stop and,
+// when it stops because of a return or an error, the object to pass up.
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+	result := eval(body, env)
+	if result == nil {
+		return nil, false
+	}
+
+	switch result.Type() {
+	case object.BREAK_OBJ:
+		return nil, true
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
 func nativeBooleanToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -123,6 +303,8 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalBitwiseNotPrefixOperatorExpression(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -132,6 +314,12 @@ func evalInfixExpression(operator string, left, right object.Object, env *object
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isInteger(left) && isInteger(right):
+		return evalBigIntInfixExpression(operator, left, right)
+	case isNumeric(left) && isNumeric(right):
+		return evalFloatInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBooleanToBooleanObject(left == right)
 	case operator == "!=":
@@ -149,17 +337,37 @@ func evalIntegerInfixExpression(operator string, left, rigth object.Object) obje
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftValue + rigthValue}
+		return object.AddIntegers(leftValue, rigthValue)
 	case "-":
-		return &object.Integer{Value: leftValue - rigthValue}
+		return object.SubIntegers(leftValue, rigthValue)
 	case "*":
-		return &object.Integer{Value: leftValue * rigthValue}
+		return object.MulIntegers(leftValue, rigthValue)
 	case "/":
-		return &object.Integer{Value: leftValue / rigthValue}
+		if rigthValue == 0 {
+			return newError("division by zero")
+		}
+		return object.DivIntegers(leftValue, rigthValue)
+	case "%":
+		if rigthValue == 0 {
+			return newError("division by zero")
+		}
+		return &object.Integer{Value: leftValue % rigthValue}
+	case "**", "<<", ">>":
+		return evalBigIntInfixExpression(operator, left, rigth)
+	case "&":
+		return &object.Integer{Value: leftValue & rigthValue}
+	case "|":
+		return &object.Integer{Value: leftValue | rigthValue}
+	case "^":
+		return &object.Integer{Value: leftValue ^ rigthValue}
 	case "<":
 		return nativeBooleanToBooleanObject(leftValue < rigthValue)
 	case ">":
 		return nativeBooleanToBooleanObject(leftValue > rigthValue)
+	case "<=":
+		return nativeBooleanToBooleanObject(leftValue <= rigthValue)
+	case ">=":
+		return nativeBooleanToBooleanObject(leftValue >= rigthValue)
 	case "==":
 		return nativeBooleanToBooleanObject(leftValue == rigthValue)
 	case "!=":
@@ -169,6 +377,147 @@ func evalIntegerInfixExpression(operator string, left, rigth object.Object) obje
 	}
 }
 
+// Integer arithmetic where at least one operand is a BigInt, or that is
+// simpler to carry out on big integers. Results that fit in 64 bits are
+// demoted back to Integer. A negative exponent gives a float, and a negative
+// shift count is an error.
+func evalBigIntInfixExpression(operator string, left, right object.Object) object.Object {
+	leftValue := object.ToBigInt(left)
+	rightValue := object.ToBigInt(right)
+
+	switch operator {
+	case "+":
+		return object.NewInteger(new(big.Int).Add(leftValue, rightValue))
+	case "-":
+		return object.NewInteger(new(big.Int).Sub(leftValue, rightValue))
+	case "*":
+		return object.NewInteger(new(big.Int).Mul(leftValue, rightValue))
+	case "/":
+		if rightValue.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return object.NewInteger(new(big.Int).Quo(leftValue, rightValue))
+	case "%":
+		if rightValue.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return object.NewInteger(new(big.Int).Rem(leftValue, rightValue))
+	case "**":
+		if rightValue.Sign() < 0 {
+			return evalFloatInfixExpression(operator, left, right)
+		}
+		result, ok := object.PowInteger(leftValue, rightValue)
+		if !ok {
+			return newError("integer too large")
+		}
+		return result
+	case "<<", ">>":
+		if rightValue.Sign() < 0 {
+			return newError("negative shift count: %s", right.Inspect())
+		}
+		result, ok := object.ShiftInteger(leftValue, rightValue, operator == "<<")
+		if !ok {
+			return newError("integer too large")
+		}
+		return result
+	case "&":
+		return object.NewInteger(new(big.Int).And(leftValue, rightValue))
+	case "|":
+		return object.NewInteger(new(big.Int).Or(leftValue, rightValue))
+	case "^":
+		return object.NewInteger(new(big.Int).Xor(leftValue, rightValue))
+	case "<":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) < 0)
+	case ">":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) > 0)
+	case "<=":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) <= 0)
+	case ">=":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) >= 0)
+	case "==":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) == 0)
+	case "!=":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// Arithmetic mixing integers and floats promotes the integer operand to a
+// float, and so does the result. Float division follows IEEE 754, dividing
+// by zero gives an infinity or NaN rather than an error.
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftValue + rightValue}
+	case "-":
+		return &object.Float{Value: leftValue - rightValue}
+	case "*":
+		return &object.Float{Value: leftValue * rightValue}
+	case "/":
+		return &object.Float{Value: leftValue / rightValue}
+	case "%":
+		return &object.Float{Value: math.Mod(leftValue, rightValue)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftValue, rightValue)}
+	case "<":
+		return nativeBooleanToBooleanObject(leftValue < rightValue)
+	case ">":
+		return nativeBooleanToBooleanObject(leftValue > rightValue)
+	case "<=":
+		return nativeBooleanToBooleanObject(leftValue <= rightValue)
+	case ">=":
+		return nativeBooleanToBooleanObject(leftValue >= rightValue)
+	case "==":
+		return nativeBooleanToBooleanObject(leftValue == rightValue)
+	case "!=":
+		return nativeBooleanToBooleanObject(leftValue != rightValue)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isInteger(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIGINT_OBJ
+}
+
+func isNumeric(obj object.Object) bool {
+	return isInteger(obj) || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftValue + rightValue}
+	case "==":
+		return nativeBooleanToBooleanObject(leftValue == rightValue)
+	case "!=":
+		return nativeBooleanToBooleanObject(leftValue != rightValue)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
 func evalBangOperatorExpression(right object.Object) object.Object {
 	switch right {
 	case TRUE:
@@ -183,37 +532,320 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		return object.NegateInteger(right.Value)
+	case *object.BigInt:
+		return object.NewInteger(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
+}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+func evalBitwiseNotPrefixOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: ^right.Value}
+	case *object.BigInt:
+		return object.NotInteger(right.Value)
+	default:
+		return newError("unknown operator: ~%s", right.Type())
+	}
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+	condition := eval(ie.Condition, env)
 
-	if isError(condition) {
+	if isAbrupt(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	val, ok := env.Get(node.Value)
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+
+	if builtin := object.GetBuiltinByName(node.Value); builtin != nil {
+		return builtin
+	}
+
+	return newError("identifier not found: %s", node.Value)
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newError("index operator not supported: %s[%s]", left.Type(), index.Type())
+	}
+}
+
+// Negative indexes count from the end of the array, so xs[-1] is the last
+// element. Indexes out of bounds in either direction evaluate to NULL.
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	elements := array.(*object.Array).Elements
+	idx := index.(*object.Integer).Value
+	length := int64(len(elements))
+
+	if idx < 0 {
+		idx += length
+	}
+
+	if idx < 0 || idx >= length {
+		return NULL
+	}
+
+	return elements[idx]
+}
+
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
 	if !ok {
-		return newError("identifier not found: " + node.Value)
+		return NULL
+	}
+
+	return pair.Value
+}
+
+// Evaluates an assignment to a variable or to an element of an array or
+// hash. A compound assignment like x += y applies the operator to the
+// current value first. Evaluates to the assigned value.
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := ast.Unparen(node.Target).(type) {
+	case *ast.Identifier:
+		current, ok := env.Get(target.Value)
+		if !ok {
+			return locateError(newError("assignment to undeclared variable: %s", target.Value), target)
+		}
+		if env.IsConst(target.Value) {
+			return locateError(newError("assignment to constant: %s", target.Value), target)
+		}
+
+		value := eval(node.Value, env)
+		if isAbrupt(value) {
+			return value
+		}
+
+		if node.Operator != "=" {
+			value = applyCompoundOperator(node, current, value, env)
+			if isError(value) {
+				return value
+			}
+		}
+
+		env.Assign(target.Value, value)
+		return value
+
+	case *ast.IndexExpression:
+		left := eval(target.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+
+		index := eval(target.Index, env)
+		if isAbrupt(index) {
+			return index
+		}
+
+		value := eval(node.Value, env)
+		if isAbrupt(value) {
+			return value
+		}
+
+		if node.Operator != "=" {
+			current := evalIndexExpression(left, index)
+			if isError(current) {
+				return locateError(current, target)
+			}
+			value = applyCompoundOperator(node, current, value, env)
+			if isError(value) {
+				return value
+			}
+		}
+
+		return locateError(evalIndexAssignment(left, index, value), target)
+
+	default:
+		return locateError(newError("cannot assign to %s", node.Target.String()), node)
 	}
+}
 
-	return val
+// Applies the operator of a compound assignment, the "+" of "+=".
+func applyCompoundOperator(node *ast.AssignExpression, current, value object.Object, env *object.Environment) object.Object {
+	operator := strings.TrimSuffix(node.Operator, "=")
+	return locateError(evalInfixExpression(operator, current, value, env), node)
+}
+
+// Stores value at index of an array or hash. Unlike reading, writing out of
+// the bounds of an array is an error.
+func evalIndexAssignment(left, index, value object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		elements := left.(*object.Array).Elements
+		idx := index.(*object.Integer).Value
+		length := int64(len(elements))
+
+		if idx < 0 {
+			idx += length
+		}
+		if idx < 0 || idx >= length {
+			return newError("index out of range: %d", index.(*object.Integer).Value)
+		}
+
+		elements[idx] = value
+		return value
+
+	case left.Type() == object.HASH_OBJ:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+
+		left.(*object.Hash).Pairs[key.HashKey()] = object.HashPair{Key: index, Value: value}
+		return value
+
+	default:
+		return newError("index assignment not supported: %s[%s]", left.Type(), index.Type())
+	}
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	pairs := make(map[object.HashKey]object.HashPair)
+
+	for _, pair := range node.Pairs {
+		key := eval(pair.Key, env)
+		if isAbrupt(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return locateError(newError("unusable as hash key: %s", key.Type()), pair.Key)
+		}
+
+		value := eval(pair.Value, env)
+		if isAbrupt(value) {
+			return value
+		}
+
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: pairs}
+}
+
+func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+
+	for _, e := range exps {
+		evaluated := eval(e, env)
+		if isAbrupt(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+
+	return result
+}
+
+func applyFunction(fn object.Object, args []object.Object) object.Object {
+	switch function := fn.(type) {
+	case *object.Function:
+		return applyUserFunction(function, args)
+	case *object.Builtin:
+		if result := function.Fn(args...); result != nil {
+			return result
+		}
+		return NULL
+	default:
+		return newError("not a function: %s", fn.Type())
+	}
+}
+
+func applyUserFunction(function *object.Function, args []object.Object) object.Object {
+	if len(args) != len(function.Parameters) {
+		return newError("wrong number of arguments: want=%d, got=%d",
+			len(function.Parameters), len(args))
+	}
+
+	extendedEnv := extendFunctionEnv(function, args)
+	evaluated := eval(function.Body, extendedEnv)
+	if evaluated == nil {
+		// The body is empty or ends with a statement
+		return NULL
+	}
+	return unwrapReturnValue(evaluated)
+}
+
+// Binds the call arguments to the function parameters in a new environment
+// enclosed by the one the function was defined in.
+func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+	env := object.NewEnclosedEnvironment(fn.Env)
+
+	for paramIdx, param := range fn.Parameters {
+		env.Set(param.Value, args[paramIdx])
+	}
+
+	return env
+}
+
+// Keeps a return statement inside the function body from bubbling up and
+// stopping the evaluation of the caller.
+func unwrapReturnValue(obj object.Object) object.Object {
+	if returnValue, ok := obj.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+
+	return obj
+}
+
+// Evaluates `&&` and `||`. The right operand is only evaluated when the left
+// one does not decide the result; either way the result is a boolean.
+func evalLogicalExpression(le *ast.LogicalExpression, env *object.Environment) object.Object {
+	left := eval(le.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	switch le.Operator {
+	case "&&":
+		if !isTruthy(left) {
+			return FALSE
+		}
+	case "||":
+		if isTruthy(left) {
+			return TRUE
+		}
+	default:
+		return locateError(newError("unknown operator: %s", le.Operator), le)
+	}
+
+	right := eval(le.Right, env)
+	if isAbrupt(right) {
+		return right
+	}
+	return nativeBooleanToBooleanObject(isTruthy(right))
 }
 
 func isTruthy(obj object.Object) bool {
@@ -233,9 +865,33 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// Attaches the position of node to obj when obj is an error that does not
+// know where it was raised yet. Errors coming from nested nodes keep their
+// own, more precise, position.
+func locateError(obj object.Object, node ast.Node) object.Object {
+	if errObj, ok := obj.(*object.Error); ok && !errObj.Pos.IsValid() {
+		errObj.Pos = node.Pos()
+	}
+	return obj
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
 	}
 	return false
 }
+
+// Reports whether obj cuts the evaluation of the enclosing expression short:
+// an error, a return, or a break or continue on its way to its loop. Such
+// objects are passed up unchanged, like errors, until a statement list or a
+// loop handles them.
+func isAbrupt(obj object.Object) bool {
+	if obj != nil {
+		switch obj.Type() {
+		case object.ERROR_OBJ, object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+			return true
+		}
+	}
+	return false
+}
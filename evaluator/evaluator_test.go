@@ -7,6 +7,7 @@ import (
 	"github.com/jolisper/monkey/lexer"
 	"github.com/jolisper/monkey/object"
 	"github.com/jolisper/monkey/parser"
+	"github.com/jolisper/monkey/token"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -29,6 +30,9 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 10 % 4 * 3", 8},
 	}
 
 	for _, tt := range tests {
@@ -37,6 +41,138 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 - 2.5", 7.5},
+		{"2 * 1.25", 2.5},
+		{"7 / 2.0", 3.5},
+		{"1e3 / 10", 100},
+		{"(1 + 2) * 0.5", 1.5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestNumericPromotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"7 / 2", "3"},
+		{"7 / 2.0", "3.5"},
+		{"2.0 * 3", "6.0"},
+		{"1e21", "1e+21"},
+		{"0.1 + 0.2", "0.30000000000000004"},
+		{"1 / 0.0", "+Inf"},
+		{"7.5 % 2", "1.5"},
+		{"123456789012345678901234567890 % 11", "7"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestBigIntegerPromotion(t *testing.T) {
+	tests := []struct {
+		input        string
+		expected     string
+		expectedType object.ObjectType
+	}{
+		{"9223372036854775807", "9223372036854775807", object.INTEGER_OBJ},
+		{"9223372036854775807 + 1", "9223372036854775808", object.BIGINT_OBJ},
+		{"-9223372036854775807 - 2", "-9223372036854775809", object.BIGINT_OBJ},
+		{"-9223372036854775808", "-9223372036854775808", object.INTEGER_OBJ},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808", object.BIGINT_OBJ},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808", object.BIGINT_OBJ},
+		{"4294967296 * 4294967296", "18446744073709551616", object.BIGINT_OBJ},
+		{"4294967296 * 4294967296 / 4294967296", "4294967296", object.INTEGER_OBJ},
+		{"9223372036854775807 + 1 - 1", "9223372036854775807", object.INTEGER_OBJ},
+		{"99999999999999999999 - 99999999999999999998", "1", object.INTEGER_OBJ},
+		{"-99999999999999999999", "-99999999999999999999", object.BIGINT_OBJ},
+		{"99999999999999999999 > 1", "true", object.BOOLEAN_OBJ},
+		{"1 < -99999999999999999999", "false", object.BOOLEAN_OBJ},
+		{"99999999999999999999 == 99999999999999999999", "true", object.BOOLEAN_OBJ},
+		{"99999999999999999999 * 0.5", "5e+19", object.FLOAT_OBJ},
+		{"let f = fn(n) { if (n < 2) { 1 } else { n * f(n - 1) } }; f(25)", "15511210043330985984000000", object.BIGINT_OBJ},
+		{`{99999999999999999999: "big"}[99999999999999999999]`, "big", object.STRING_OBJ},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Type() != tt.expectedType {
+			t.Errorf("wrong type for %q. want=%s, got=%s (%s)", tt.input, tt.expectedType, evaluated.Type(), evaluated.Inspect())
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestIntegerOperators(t *testing.T) {
+	tests := []struct {
+		input        string
+		expected     string
+		expectedType object.ObjectType
+	}{
+		{"2 ** 10", "1024", object.INTEGER_OBJ},
+		{"2 ** 3 ** 2", "512", object.INTEGER_OBJ},
+		{"(-2) ** 3", "-8", object.INTEGER_OBJ},
+		{"-2 ** 2", "4", object.INTEGER_OBJ},
+		{"2 ** 64", "18446744073709551616", object.BIGINT_OBJ},
+		{"2 ** -1", "0.5", object.FLOAT_OBJ},
+		{"2.0 ** 0.5", "1.4142135623730951", object.FLOAT_OBJ},
+		{"1 ** 99999999999999999999", "1", object.INTEGER_OBJ},
+		{"6 & 3", "2", object.INTEGER_OBJ},
+		{"6 | 3", "7", object.INTEGER_OBJ},
+		{"6 ^ 3", "5", object.INTEGER_OBJ},
+		{"~5", "-6", object.INTEGER_OBJ},
+		{"~-1", "0", object.INTEGER_OBJ},
+		{"1 << 4", "16", object.INTEGER_OBJ},
+		{"1 << 63", "9223372036854775808", object.BIGINT_OBJ},
+		{"-16 >> 2", "-4", object.INTEGER_OBJ},
+		{"-1 >> 100", "-1", object.INTEGER_OBJ},
+		{"5 >> 99999999999999999999", "0", object.INTEGER_OBJ},
+		{"0 << 99999999999999999999", "0", object.INTEGER_OBJ},
+		{"(1 << 70) >> 70", "1", object.INTEGER_OBJ},
+		{"(1 << 64) | 1", "18446744073709551617", object.BIGINT_OBJ},
+		{"~(1 << 64)", "-18446744073709551617", object.BIGINT_OBJ},
+		{"1 + 2 << 1", "6", object.INTEGER_OBJ},
+		{"1 | 2 == 3", "true", object.BOOLEAN_OBJ},
+		{"2 <= 2", "true", object.BOOLEAN_OBJ},
+		{"3 <= 2", "false", object.BOOLEAN_OBJ},
+		{"2 >= 3", "false", object.BOOLEAN_OBJ},
+		{"1.5 >= 1", "true", object.BOOLEAN_OBJ},
+		{"99999999999999999999 >= 99999999999999999999", "true", object.BOOLEAN_OBJ},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Type() != tt.expectedType {
+			t.Errorf("wrong type for %q. want=%s, got=%s (%s)", tt.input, tt.expectedType, evaluated.Type(), evaluated.Inspect())
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -64,6 +200,11 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == false", true},
 		{"(1 > 2) == true", false},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"1.0 != 1", false},
+		{"0.1 + 0.2 == 0.3", false},
 	}
 
 	for _, tt := range tests {
@@ -91,6 +232,31 @@ func TestBangOperator(t *testing.T) {
 	}
 }
 
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && \"a\"", true},
+		{"if (false) { 1 } || 0", true},
+		{"1 < 2 && 2 < 3", true},
+		{"false || true && false", false},
+		// the right operand is not evaluated when the left one decides
+		{"false && missing", false},
+		{"true || missing", true},
+		{"false && (1 / 0)", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestIfElseExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -159,6 +325,10 @@ func TestErrorHandling(t *testing.T) {
 			"-true",
 			"unknown operator: -BOOLEAN",
 		},
+		{
+			"1.5 + true",
+			"type mismatch: FLOAT + BOOLEAN",
+		},
 		{
 			"true + false;",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -186,6 +356,78 @@ if (10 > 1) {
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			`"Hello" - "World"`,
+			"unknown operator: STRING - STRING",
+		},
+		{
+			`"Hello" + 1`,
+			"type mismatch: STRING + INTEGER",
+		},
+		{
+			`[1, 2][true]`,
+			"index operator not supported: ARRAY[BOOLEAN]",
+		},
+		{
+			`{"name": "Monkey"}[fn(x) { x }];`,
+			"unusable as hash key: FUNCTION",
+		},
+		{
+			`{fn(x) { x }: 1};`,
+			"unusable as hash key: FUNCTION",
+		},
+		{
+			`5[0]`,
+			"index operator not supported: INTEGER[INTEGER]",
+		},
+		{
+			"let x = 5; x(1);",
+			"not a function: INTEGER",
+		},
+		{
+			"let f = fn(a, b) { a + b; }; f(1);",
+			"wrong number of arguments: want=2, got=1",
+		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"10 % (5 - 5)",
+			"division by zero",
+		},
+		{
+			"123456789012345678901234567890 / 0",
+			"division by zero",
+		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"1 >> -99999999999999999999",
+			"negative shift count: -99999999999999999999",
+		},
+		{
+			"1 << 99999999999999999999",
+			"integer too large",
+		},
+		{
+			"3 ** 99999999999",
+			"integer too large",
+		},
+		{
+			"1.5 & 1",
+			"unknown operator: FLOAT & INTEGER",
+		},
+		{
+			"~true",
+			"unknown operator: ~BOOLEAN",
+		},
+		{
+			`"a" <= "b"`,
+			"unknown operator: STRING <= STRING",
+		},
 	}
 
 	for _, tt := range tests {
@@ -203,11 +445,137 @@ if (10 > 1) {
 	}
 }
 
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos token.Position
+	}{
+		{"foobar", token.Position{Offset: 0, Line: 1, Column: 1}},
+		{"let a = 1;\nlet b = a + true;", token.Position{Offset: 19, Line: 2, Column: 9}},
+		{"let f = fn() {\n  -true;\n};\nf();", token.Position{Offset: 17, Line: 2, Column: 3}},
+		{"let f = fn(x) { x; };\nf(1, 2);", token.Position{Offset: 22, Line: 2, Column: 1}},
+		{"let n = 0;\n10 / n;", token.Position{Offset: 11, Line: 2, Column: 1}},
+		{"const c = 1;\nc = 2;", token.Position{Offset: 13, Line: 2, Column: 1}},
+		{"const c = 1;\nlet c = 2;", token.Position{Offset: 17, Line: 2, Column: 5}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Pos != tt.expectedPos {
+			t.Errorf("wrong error position. expected=%+v, got=%+v", tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 5) { let i = i + 1; }; i", 5},
+		{"let i = 0; while (false) { let i = 1; }; i", 0},
+		{"let sum = 0; for (x in [1, 2, 3]) { let sum = sum + x; }; sum", 6},
+		{"let n = 0; for (x in []) { let n = n + 1; }; n", 0},
+		{`let s = ""; for (c in "añb") { let s = c + s; }; len(s)`, 3},
+		{`let s = ""; for (k in {"b": 1, "a": 2}) { let s = s + k; }; if (s == "ab") { 1 }`, 1},
+		{"let i = 0; while (true) { let i = i + 1; if (i == 3) { break; } }; i", 3},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x % 2 == 0) { continue; } let sum = sum + x; }; sum", 4},
+		{`let n = 0;
+for (x in [1, 2, 3]) {
+	for (y in [1, 2, 3]) {
+		if (y > x) { break; }
+		let n = n + 1;
+	}
+}
+n`, 6},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10; } } 0 }; f()", 20},
+		{"let f = fn() { while (false) { } }; f()", nil},
+		{"let i = 0; while (i < 100000) { let i = i + 1; }; i", 100000},
+		{"for (x in 5) { }", "not iterable: INTEGER"},
+		{"while (1 + true) { }", "type mismatch: INTEGER + BOOLEAN"},
+		{"let i = 0; while (true) { let i = i + 1; if (i > 2) { i + false } }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		testBuiltinResult(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = 1; a = 2; a", 2},
+		{"let a = 1; a = 2", 2},
+		{"let a = 1; let b = 2; a = b = 3; a + b", 6},
+		{"let i = 0; while (i < 5) { i += 1 }; i", 5},
+		{"let a = 10; a -= 3; a *= 2; a /= 7; a", 2},
+		{"let a = 7; a %= 4; a **= 3; a", 27},
+		{"let a = 6; a &= 3; a |= 8; a ^= 1; a", 11},
+		{"let a = 1; a <<= 4; a >>= 2; a", 4},
+		{"let a = 1; let f = fn() { a = a + 1 }; f(); f(); a", 3},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c()", 2},
+		{"let a = 1; let f = fn() { let a = 5; a = 6 }; f(); a", 1},
+		{"let arr = [1, 2, 3]; arr[0] = 5; arr[-1] += 10; arr[0] + arr[2]", 18},
+		{`let h = {"a": 1}; h["a"] += 1; h["b"] = 5; h["a"] + h["b"]`, 7},
+		{"b = 1", "assignment to undeclared variable: b"},
+		{"len = 1", "assignment to undeclared variable: len"},
+		{"let a = 1; a += true", "type mismatch: INTEGER + BOOLEAN"},
+		{"let arr = [1]; arr[1] = 2", "index out of range: 1"},
+		{`let h = {}; h[[1]] = 2`, "unusable as hash key: ARRAY"},
+		{`let s = "ab"; s[0] = "c"`, "index assignment not supported: STRING[INTEGER]"},
+	}
+
+	for _, tt := range tests {
+		testBuiltinResult(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"const a = 5; a", 5},
+		{"const a = 5; let f = fn() { let a = 6; a }; f() + a", 11},
+		{"const a = 5; let f = fn(a) { a = a + 1 }; f(1)", 2},
+		{"const arr = [1]; arr[0] = 2; arr[0]", 2},
+		{"let a = 1; const a = 2; a", 2},
+		{"const a = 5; a = 6", "assignment to constant: a"},
+		{"const a = 5; a += 1", "assignment to constant: a"},
+		{"const a = 5; let f = fn() { a = 6 }; f()", "assignment to constant: a"},
+		{"const a = 5; let a = 6", "redeclaration of constant: a"},
+		{"const a = 5; const a = 6", "redeclaration of constant: a"},
+		{"const a = 5; if (true) { let a = 6 }", "redeclaration of constant: a"},
+		{"const x = 0; for (x in [1]) { }", "redeclaration of constant: x"},
+		// each iteration of a loop runs the same declaration again
+		{"for (x in [1, 2, 3]) { const c = x * 2; }; c", 6},
+		{"let i = 0; while (i < 3) { i += 1; if (true) { const k = i; } }; k", 3},
+		{"for (x in [1, 2]) { const c = x; c = 5 }", "assignment to constant: c"},
+		{"for (x in [1, 2]) { const c = x; let c = 1 }", "redeclaration of constant: c"},
+		{"while (true) { const c = 1; const c = 2 }", "redeclaration of constant: c"},
+	}
+
+	for _, tt := range tests {
+		testBuiltinResult(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected int64
 	}{
+		{"let año = 2024; año + 1;", 2025},
+		{"let x1 = 1; let x2 = 2; x1 + x2;", 3},
 		{"let a = 5; a;", 5},
 		{"let a = 5 * 5; a;", 25},
 		{"let a = 5; let b = a; b;", 5},
@@ -220,6 +588,358 @@ func TestLetStatements(t *testing.T) {
 
 }
 
+func TestFunctionObject(t *testing.T) {
+	input := "fn(x) { x + 2; };"
+
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(fn.Parameters) != 1 {
+		t.Fatalf("function has wrong parameters. Parameters=%+v", fn.Parameters)
+	}
+
+	if fn.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", fn.Parameters[0])
+	}
+
+	expectedBody := "(x + 2)"
+
+	if fn.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, fn.Body.String())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let identity = fn(x) { return x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+		{"let f = fn() { return 1; 2; }; f() + 10;", 11},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+let newAdder = fn(x) {
+  fn(y) { x + y };
+};
+
+let addTwo = newAdder(2);
+addTwo(2);`, 4},
+		{`
+let add = fn(a, b) { a + b };
+let applyFunc = fn(a, b, func) { func(a, b) };
+applyFunc(2, 2, add);`, 4},
+		{`
+let x = 10;
+let shadow = fn(x) { x * 2 };
+shadow(1) + x;`, 12},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestStringLiteral(t *testing.T) {
+	input := `"Hello World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	input := `"Hello" + " " + "World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"a" != "a"`, false},
+		{`let s = "mon"; s + "key" == "monkey"`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{`len("a\u{f1}o")`, 3},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`puts("hello", "world!")`, nil},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, nil},
+		{`first(1)`, "argument to `first` must be ARRAY, got INTEGER"},
+		{`rest([1, 2, 3])`, []int{2, 3}},
+		{`rest([])`, nil},
+		{`rest("abc")`, "argument to `rest` must be ARRAY, got STRING"},
+		{`push([], 1)`, []int{1}},
+		{`let a = [1]; push(a, 2); a`, []int{1}},
+		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+		{`let len = fn(x) { 42 }; len("abc")`, 42},
+	}
+
+	for _, tt := range tests {
+		testBuiltinResult(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	evaluator.RegisterBuiltin("double", func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return evaluator.NewError("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		integer, ok := args[0].(*object.Integer)
+		if !ok {
+			return evaluator.NewError("argument to `double` must be INTEGER, got %s", args[0].Type())
+		}
+		return &object.Integer{Value: integer.Value * 2}
+	})
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`double(21)`, 42},
+		{`let f = double; f(2) + 1`, 5},
+		{`double("x")`, "argument to `double` must be INTEGER, got STRING"},
+		{`let double = fn(x) { x }; double(1)`, 1},
+	}
+
+	for _, tt := range tests {
+		testBuiltinResult(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	evaluator.RegisterBuiltin("explode", func(args ...object.Object) object.Object {
+		panic("boom")
+	})
+
+	evaluated := testEval(`let x = 1; explode(x);`)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%v)", evaluated, evaluated)
+	}
+	if errObj.Message != "internal error: boom" {
+		t.Errorf("wrong error message. expected=%q, got=%q", "internal error: boom", errObj.Message)
+	}
+}
+
+func testBuiltinResult(t *testing.T, input string, evaluated object.Object, expected interface{}) {
+	switch expected := expected.(type) {
+	case int:
+		testIntegerObject(t, evaluated, int64(expected))
+	case nil:
+		testNullObject(t, evaluated)
+	case string:
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: object is not Error. got=%T (%+v)", input, evaluated, evaluated)
+			return
+		}
+		if errObj.Message != expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		}
+	case []int:
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Errorf("%s: obj not Array. got=%T (%+v)", input, evaluated, evaluated)
+			return
+		}
+
+		if len(array.Elements) != len(expected) {
+			t.Errorf("wrong num of elements. want=%d, got=%d", len(expected), len(array.Elements))
+			return
+		}
+
+		for i, expectedElem := range expected {
+			testIntegerObject(t, array.Elements[i], int64(expectedElem))
+		}
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+
+	if result.Inspect() != "[1, 4, 6]" {
+		t.Errorf("array.Inspect() wrong. got=%q", result.Inspect())
+	}
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let myArray = [1, 2, 3]; myArray[2];", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-3]", 1},
+		{"[1, 2, 3][-4]", nil},
+		{"[][0]", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		evaluator.TRUE.HashKey():                   5,
+		evaluator.FALSE.HashKey():                  6,
+	}
+
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+}
+
+func TestHashInspect(t *testing.T) {
+	input := `{"b": 2, 10: "ten", "a": 1, 2: "two", true: 0, -1: "minus"}`
+
+	expected := "{true: 0, -1: minus, 2: two, 10: ten, a: 1, b: 2}"
+
+	evaluated := testEval(input)
+	if evaluated.Inspect() != expected {
+		t.Errorf("hash.Inspect() wrong. expected=%q, got=%q", expected, evaluated.Inspect())
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{"a": 1, "a": 2}["a"]`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	result, ok := obj.(*object.Integer)
 	if !ok {
@@ -235,6 +955,21 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
@@ -0,0 +1,245 @@
+package evaluator
+
+import (
+	"strconv"
+
+	"github.com/jolisper/monkey/ast"
+	"github.com/jolisper/monkey/object"
+	"github.com/jolisper/monkey/token"
+)
+
+// Reports whether call is a call of quote, which is not a function: its
+// argument is not evaluated.
+func isQuoteCall(call *ast.CallExpression) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == "quote"
+}
+
+func isUnquoteCall(node ast.Node) bool {
+	call, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == "unquote"
+}
+
+// Evaluates quote(x) to the code of x. The calls of unquote inside x are
+// evaluated in env and replaced by their values; x itself is copied first,
+// so that a quote evaluated again, e.g. in a loop, starts over.
+func evalQuote(call *ast.CallExpression, env *object.Environment) object.Object {
+	if len(call.Arguments) != 1 {
+		return locateError(newError("wrong number of arguments to quote: want=1, got=%d", len(call.Arguments)), call)
+	}
+
+	var err object.Object
+	node := ast.Modify(ast.Copy(call.Arguments[0]), func(node ast.Node) ast.Node {
+		if err != nil || !isUnquoteCall(node) {
+			return node
+		}
+
+		unquote := node.(*ast.CallExpression)
+		if len(unquote.Arguments) != 1 {
+			err = locateError(newError("wrong number of arguments to unquote: want=1, got=%d", len(unquote.Arguments)), unquote)
+			return node
+		}
+
+		value := eval(unquote.Arguments[0], env)
+		if isError(value) {
+			err = value
+			return node
+		}
+
+		replacement := objectToExpression(value, unquote)
+		if replacement == nil {
+			err = locateError(newError("cannot unquote %s", value.Type()), unquote)
+			return node
+		}
+		return replacement
+	})
+	if err != nil {
+		return err
+	}
+
+	return &object.Quote{Node: node}
+}
+
+// Returns the code of a literal with the value of obj, nil when obj has no
+// literal form. The nodes made take the span of the unquote call they
+// replace; quotes give back the code they hold.
+func objectToExpression(obj object.Object, unquote *ast.CallExpression) ast.Expression {
+	pos, end := unquote.Pos(), unquote.End()
+
+	switch obj := obj.(type) {
+	case *object.Integer:
+		literal := strconv.FormatInt(obj.Value, 10)
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal, Pos: pos, End: end}, Value: obj.Value}
+
+	case *object.BigInt:
+		literal := obj.Value.String()
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal, Pos: pos, End: end}, Big: obj.Value}
+
+	case *object.Float:
+		return &ast.FloatLiteral{Token: token.Token{Type: token.FLOAT, Literal: obj.Inspect(), Pos: pos, End: end}, Value: obj.Value}
+
+	case *object.String:
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: obj.Value, Pos: pos, End: end}, Value: obj.Value}
+
+	case *object.Boolean:
+		if obj.Value {
+			return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true", Pos: pos, End: end}, Value: true}
+		}
+		return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false", Pos: pos, End: end}, Value: false}
+
+	case *object.Array:
+		elements := []ast.Expression{}
+		for _, e := range obj.Elements {
+			element := objectToExpression(e, unquote)
+			if element == nil {
+				return nil
+			}
+			elements = append(elements, element)
+		}
+		return &ast.ArrayLiteral{Token: token.Token{Type: token.LBRACKET, Literal: "[", Pos: pos}, Elements: elements, Rbrack: unquote.Rparen}
+
+	case *object.Hash:
+		pairs := []ast.HashPair{}
+		for _, pair := range obj.SortedPairs() {
+			key := objectToExpression(pair.Key, unquote)
+			value := objectToExpression(pair.Value, unquote)
+			if key == nil || value == nil {
+				return nil
+			}
+			pairs = append(pairs, ast.HashPair{Key: key, Value: value})
+		}
+		return &ast.HashLiteral{Token: token.Token{Type: token.LBRACE, Literal: "{", Pos: pos}, Pairs: pairs, Rbrace: unquote.Rparen}
+
+	case *object.Quote:
+		if e, ok := obj.Node.(ast.Expression); ok {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// DefineMacros binds in env the macros defined by the top-level let and
+// const statements of program, and removes those statements from it.
+// Macros defined anywhere else are an error when they are evaluated.
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	statements := program.Statements[:0]
+
+	for _, statement := range program.Statements {
+		let, ok := statement.(*ast.LetStatement)
+		if !ok {
+			statements = append(statements, statement)
+			continue
+		}
+		literal, ok := ast.Unparen(let.Value).(*ast.MacroLiteral)
+		if !ok {
+			statements = append(statements, statement)
+			continue
+		}
+
+		macro := &object.Macro{Parameters: literal.Parameters, Body: literal.Body, Env: env}
+		if let.IsConst() {
+			env.SetConst(let.Name.Value, macro, let)
+		} else {
+			env.Set(let.Name.Value, macro)
+		}
+	}
+
+	program.Statements = statements
+}
+
+// ExpandMacros replaces, in place, the calls of the macros bound in env by
+// the code the macros return, and returns the expanded program. A macro is
+// evaluated with its arguments bound, unevaluated, as quotes, and must
+// evaluate to a quote of an expression itself. Calls inside the arguments
+// are expanded first; the code a macro returns is not expanded again.
+//
+// The expansion stops at the first error, which is returned.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var expandErr *object.Error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		if expandErr != nil {
+			return node
+		}
+
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+		macro, ok := macroOf(call, env)
+		if !ok {
+			return node
+		}
+
+		if len(call.Arguments) != len(macro.Parameters) {
+			expandErr = newError("wrong number of arguments: want=%d, got=%d", len(macro.Parameters), len(call.Arguments))
+			expandErr.Pos = call.Pos()
+			return node
+		}
+
+		evaluated := unwrapReturnValue(Eval(macro.Body, extendMacroEnv(macro, call.Arguments)))
+		if errObj, ok := evaluated.(*object.Error); ok {
+			locateError(errObj, call)
+			expandErr = errObj
+			return node
+		}
+
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			expandErr = newError("macro must return a quote, got %s", typeOf(evaluated))
+			expandErr.Pos = call.Pos()
+			return node
+		}
+		e, ok := quote.Node.(ast.Expression)
+		if !ok {
+			expandErr = newError("macro must return a quoted expression, got %s", quote.Inspect())
+			expandErr.Pos = call.Pos()
+			return node
+		}
+		return e
+	})
+
+	return expanded, expandErr
+}
+
+// Returns the macro called by call, when the function it calls is the name
+// of a macro.
+func macroOf(call *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := env.Get(ident.Value)
+	if !ok {
+		return nil, false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
+
+// Binds the arguments of a macro call, quoted, to the macro parameters in a
+// new environment enclosed by the one the macro was defined in.
+func extendMacroEnv(macro *object.Macro, args []ast.Expression) *object.Environment {
+	env := object.NewEnclosedEnvironment(macro.Env)
+
+	for paramIdx, param := range macro.Parameters {
+		env.Set(param.Value, &object.Quote{Node: args[paramIdx]})
+	}
+
+	return env
+}
+
+// The type of obj for error messages, where a body without value is NULL.
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+	return obj.Type()
+}
@@ -0,0 +1,296 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/jolisper/monkey/ast"
+	"github.com/jolisper/monkey/evaluator"
+	"github.com/jolisper/monkey/lexer"
+	"github.com/jolisper/monkey/object"
+	"github.com/jolisper/monkey/parser"
+	"github.com/jolisper/monkey/token"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(5 + 8)`, `(5 + 8)`},
+		{`quote(foobar)`, `foobar`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+	}
+
+	for _, tt := range tests {
+		testQuoteObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(unquote(4))`, `4`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`quote(unquote(4 + 4) + 8)`, `(8 + 8)`},
+		{`let foobar = 8; quote(foobar)`, `foobar`},
+		{`let foobar = 8; quote(unquote(foobar))`, `8`},
+		{`quote(unquote(true))`, `true`},
+		{`quote(unquote(true == false))`, `false`},
+		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+		{`let quotedInfix = quote(4 + 4); quote(unquote(4 + 4) + unquote(quotedInfix))`, `(8 + (4 + 4))`},
+		{`quote(unquote(1.5) * unquote(99999999999999999999))`, `(1.5 * 99999999999999999999)`},
+		{`quote(unquote([1, "a"]))`, `[1, a]`},
+		{`quote(unquote({"b": 2, "a": 1}))`, `{a: 1, b: 2}`},
+		{`quote(fn(x) { unquote(1 + 2) })`, `fn(x) 3`},
+		// The quoted code is copied, the next evaluation sees the unquote again
+		{`let f = fn(x) { quote(unquote(x) + 1) }; f(1); f(2)`, `(2 + 1)`},
+	}
+
+	for _, tt := range tests {
+		testQuoteObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func testQuoteObject(t *testing.T, input string, evaluated object.Object, expected string) {
+	t.Helper()
+
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		t.Errorf("expected *object.Quote for %q. got=%T (%+v)", input, evaluated, evaluated)
+		return
+	}
+
+	if quote.Node == nil {
+		t.Errorf("quote.Node is nil for %q", input)
+		return
+	}
+
+	if quote.Node.String() != expected {
+		t.Errorf("wrong quoted code for %q. want=%q, got=%q", input, expected, quote.Node.String())
+	}
+}
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	const constmacro = macro() { quote(1) };
+	let parenmacro = (macro(x) { x });
+	`
+
+	env := object.NewEnvironment()
+	program := testParseProgram(input)
+
+	evaluator.DefineMacros(program, env)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	if _, ok := env.Get("number"); ok {
+		t.Fatalf("number should not be defined")
+	}
+	if _, ok := env.Get("function"); ok {
+		t.Fatalf("function should not be defined")
+	}
+
+	obj, ok := env.Get("mymacro")
+	if !ok {
+		t.Fatalf("macro not in environment.")
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("wrong number of macro parameters. got=%d", len(macro.Parameters))
+	}
+
+	if macro.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", macro.Parameters[0])
+	}
+	if macro.Parameters[1].String() != "y" {
+		t.Fatalf("parameter is not 'y'. got=%q", macro.Parameters[1])
+	}
+
+	expectedBody := "(x + y)"
+
+	if macro.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, macro.Body.String())
+	}
+
+	if !env.IsConst("constmacro") {
+		t.Fatalf("constmacro should be a constant")
+	}
+
+	obj, ok = env.Get("parenmacro")
+	if !ok {
+		t.Fatalf("parenmacro not in environment.")
+	}
+	if _, ok := obj.(*object.Macro); !ok {
+		t.Fatalf("parenmacro is not Macro. got=%T (%+v)", obj, obj)
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`
+			let infixExpression = macro() { quote(1 + 2); };
+
+			infixExpression();
+			`,
+			`(1 + 2)`,
+		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+			reverse(2 + 2, 10 - 5);
+			`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`
+			let reverse = (macro(a, b) { quote(unquote(b) - unquote(a)); });
+
+			reverse(2 + 2, 10 - 5);
+			`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`
+			let unless = macro(condition, consequence, alternative) {
+				quote(if (!(unquote(condition))) {
+					unquote(consequence);
+				} else {
+					unquote(alternative);
+				});
+			};
+
+			unless(10 > 5, puts("not greater"), puts("greater"));
+			`,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+		},
+		{
+			`
+			let twice = macro(x) { return quote(unquote(x) * 2); };
+
+			let a = twice(twice(1));
+			`,
+			`let a = ((1 * 2) * 2);`,
+		},
+	}
+
+	for _, tt := range tests {
+		expected := testParseProgram(tt.expected)
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		evaluator.DefineMacros(program, env)
+		expanded, err := evaluator.ExpandMacros(program, env)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tt.input, err.Inspect())
+			continue
+		}
+
+		if expanded.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+		}
+	}
+}
+
+func TestMacroErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedPos     token.Position
+	}{
+		{
+			"let m = macro(x) { quote(x) };\nm(1, 2);",
+			"wrong number of arguments: want=1, got=2",
+			token.Position{Offset: 31, Line: 2, Column: 1},
+		},
+		{
+			"let m = macro() { 1 };\nm();",
+			"macro must return a quote, got INTEGER",
+			token.Position{Offset: 23, Line: 2, Column: 1},
+		},
+		{
+			"let m = macro() { };\nm();",
+			"macro must return a quote, got NULL",
+			token.Position{Offset: 21, Line: 2, Column: 1},
+		},
+		{
+			"let m = macro() { quote(unquote(y)) };\nm();",
+			"identifier not found: y",
+			token.Position{Offset: 32, Line: 1, Column: 33},
+		},
+		{
+			"let m = macro() { quote(unquote(fn() {})) };\nm();",
+			"cannot unquote FUNCTION",
+			token.Position{Offset: 24, Line: 1, Column: 25},
+		},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		evaluator.DefineMacros(program, env)
+		_, err := evaluator.ExpandMacros(program, env)
+		if err == nil {
+			t.Errorf("expected an error for %q", tt.input)
+			continue
+		}
+
+		if err.Message != tt.expectedMessage {
+			t.Errorf("wrong error message for %q. want=%q, got=%q", tt.input, tt.expectedMessage, err.Message)
+		}
+		if err.Pos != tt.expectedPos {
+			t.Errorf("wrong error position for %q. want=%+v, got=%+v", tt.input, tt.expectedPos, err.Pos)
+		}
+	}
+}
+
+func TestMacroEvalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn() { macro(x) { x } }; f()", "macros must be defined by a top-level let"},
+		{"quote(1, 2)", "wrong number of arguments to quote: want=1, got=2"},
+		{"quote(unquote())", "wrong number of arguments to unquote: want=1, got=0"},
+		{"unquote(1)", "identifier not found: unquote"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message for %q. want=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
+
+func testParseProgram(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
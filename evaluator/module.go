@@ -0,0 +1,131 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jolisper/monkey/ast"
+	"github.com/jolisper/monkey/lexer"
+	"github.com/jolisper/monkey/object"
+	"github.com/jolisper/monkey/parser"
+)
+
+// SearchPath lists the directories where the files imported by a program
+// are looked for, in order, when they are not found next to the importing
+// file. Like RegisterBuiltin, it is meant to be set during program
+// initialization.
+var SearchPath []string
+
+// Appended to the import paths that have no extension
+const moduleExtension = ".mk"
+
+// Evaluates an import statement, which binds the module as a constant.
+// Importing the same module again in the same scope is allowed.
+func evalImportStatement(is *ast.ImportStatement, env *object.Environment) object.Object {
+	module := importModule(is, env.Modules())
+	if isError(module) {
+		return module
+	}
+
+	name := is.Name()
+	if env.Redeclares(name, is) {
+		if current, _ := env.Get(name); current == module {
+			return nil
+		}
+		return locateError(newError("redeclaration of constant: %s", name), is.Path)
+	}
+
+	env.SetConst(name, module, is)
+	return nil
+}
+
+// Returns the module imported by is, evaluating its file the first time it
+// is imported by the program.
+func importModule(is *ast.ImportStatement, modules *object.Modules) object.Object {
+	path, ok := resolveImport(is.Path.Value, is.Pos().Filename)
+	if !ok {
+		return locateError(newError("module not found: %s", is.Path.Value), is.Path)
+	}
+
+	if module, ok := modules.Get(path); ok {
+		return module
+	}
+
+	if cycle := modules.StartLoading(path); cycle != nil {
+		return locateError(newError("import cycle: %s", strings.Join(cycle, " -> ")), is.Path)
+	}
+	defer modules.DoneLoading()
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return locateError(newError("cannot read module: %s", err), is.Path)
+	}
+
+	p := parser.New(lexer.NewWithFilename(path, string(src)))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		return &object.Error{Message: errs[0].Msg, Pos: errs[0].Pos}
+	}
+
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	if _, errObj := ExpandMacros(program, macroEnv); errObj != nil {
+		return errObj
+	}
+
+	env := object.NewModuleEnvironment(modules)
+	if result := eval(program, env); isError(result) {
+		return result
+	}
+
+	module := &object.Module{Name: is.Name(), Path: path, Env: env}
+	modules.Set(module)
+	return module
+}
+
+// Returns the absolute path of the file imported as importPath, a slash
+// separated path, by the file importer. A relative path is looked up next
+// to the importer first, then in each directory of SearchPath.
+func resolveImport(importPath, importer string) (string, bool) {
+	file := filepath.FromSlash(importPath)
+	if filepath.Ext(file) == "" {
+		file += moduleExtension
+	}
+
+	var candidates []string
+	if filepath.IsAbs(file) {
+		candidates = []string{file}
+	} else {
+		candidates = append(candidates, filepath.Join(filepath.Dir(importer), file))
+		for _, dir := range SearchPath {
+			candidates = append(candidates, filepath.Join(dir, file))
+		}
+	}
+
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if abs, err := filepath.Abs(candidate); err == nil {
+			return abs, true
+		}
+		return candidate, true
+	}
+
+	return "", false
+}
+
+func evalMemberExpression(left object.Object, name string) object.Object {
+	module, ok := left.(*object.Module)
+	if !ok {
+		return newError("member access not supported: %s.%s", left.Type(), name)
+	}
+
+	value, ok := module.Export(name)
+	if !ok {
+		return newError("module %s does not export %s", module.Name, name)
+	}
+	return value
+}
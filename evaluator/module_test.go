@@ -0,0 +1,170 @@
+package evaluator_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jolisper/monkey/evaluator"
+	"github.com/jolisper/monkey/lexer"
+	"github.com/jolisper/monkey/object"
+	"github.com/jolisper/monkey/parser"
+)
+
+// Writes files, by slash-separated name, under a new temporary directory
+// and returns the directory.
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// Evaluates input as the file main.mk of dir.
+func testEvalFile(t *testing.T, dir, input string) object.Object {
+	t.Helper()
+
+	p := parser.New(lexer.NewWithFilename(filepath.Join(dir, "main.mk"), input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return evaluator.Eval(program, object.NewEnvironment())
+}
+
+func TestImports(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"lib/math.mk": `
+			let _square = fn(x) { x * x };
+			let square = fn(x) { _square(x) };
+			const pi = 3;
+		`,
+		"lib/geometry.mk": `
+			import "math";
+			let area = fn(r) { math.pi * math.square(r) };
+		`,
+		"lib/counter.mk": `
+			let count = 0;
+			let next = fn() { count += 1 };
+		`,
+		"lib/macros.mk": `
+			let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };
+			let check = fn(x) { unless(x, "no", "yes") };
+		`,
+	})
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "lib/math"; math.square(4)`, 16},
+		{`import "lib/math.mk"; math.pi`, 3},
+		{`import "lib/geometry"; geometry.area(2)`, 12},
+		{`import "./lib/../lib/math"; let f = fn() { math.square }; f()(3)`, 9},
+		// A module is evaluated once, every import gets the same module
+		{`import "lib/math"; import "lib/geometry"; import "lib/math"; math.square(2)`, 4},
+		{`import "lib/counter"; counter.next(); fn() { import "lib/counter"; counter.next() }()`, 2},
+		// Macros are expanded in the module, they are not exported
+		{`import "lib/macros"; macros.check(false)`, "no"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalFile(t, dir, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("wrong value for %q. want=%q, got=%q", tt.input, expected, str.Value)
+			}
+		}
+	}
+}
+
+func TestImportSearchPath(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"local/util.mk":  `let where = "local";`,
+		"shared/util.mk": `let where = "shared";`,
+		"shared/only.mk": `let where = "shared";`,
+	})
+
+	defer func(searchPath []string) { evaluator.SearchPath = searchPath }(evaluator.SearchPath)
+	evaluator.SearchPath = []string{filepath.Join(dir, "shared")}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Files next to the importing file come first
+		{`import "local/util"; util.where`, "local"},
+		{`import "util"; util.where`, "shared"},
+		{`import "only"; only.where`, "shared"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalFile(t, dir, tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("wrong value for %q. want=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"a.mk":       `import "b";`,
+		"b.mk":       `import "c";`,
+		"c.mk":       `import "b";`,
+		"bad.mk":     "let x = 1;\nlet = 2;",
+		"failing.mk": `let x = 1 + true;`,
+		"lib.mk":     `let _hidden = 1;`,
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "missing"`, "ERROR: " + path("main.mk") + ":1:8: module not found: missing"},
+		{`import "a"`, "ERROR: " + path("c.mk") + ":1:8: import cycle: " +
+			path("b.mk") + " -> " + path("c.mk") + " -> " + path("b.mk")},
+		{`import "bad"`, "ERROR: " + path("bad.mk") + ":2:5: expected next token to be IDENT, got = instead"},
+		{`import "failing"`, "ERROR: " + path("failing.mk") + ":1:9: type mismatch: INTEGER + BOOLEAN"},
+		{`import "lib"; lib._hidden`, "ERROR: " + path("main.mk") + ":1:19: module lib does not export _hidden"},
+		{`import "lib"; lib.missing`, "ERROR: " + path("main.mk") + ":1:19: module lib does not export missing"},
+		{`let lib = 1; lib.x`, "ERROR: " + path("main.mk") + ":1:18: member access not supported: INTEGER.x"},
+		{`import "lib"; lib = 2`, "ERROR: " + path("main.mk") + ":1:15: assignment to constant: lib"},
+		{`const lib = 1; import "lib"`, "ERROR: " + path("main.mk") + ":1:23: redeclaration of constant: lib"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalFile(t, dir, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("wrong error for %q.\nwant=%q\ngot= %q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jolisper/monkey/format"
+)
+
+// formatFiles implements the fmt command. Formatted programs, or their
+// diffs with -d, go to stdout; with -w the files are rewritten instead.
+func formatFiles(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	diff := flags.Bool("d", false, "print a diff of the changes instead of the formatted program")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	for _, name := range names {
+		if name == "-" && *write {
+			fmt.Fprintf(stderr, "monkey: cannot use -w with stdin\n\n%s", usage)
+			return exitUsage
+		}
+	}
+
+	status := exitOK
+	for _, name := range names {
+		src, err := readSource(name, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey: %s\n", err)
+			return exitInternal
+		}
+
+		displayName := name
+		if name == "-" {
+			displayName = stdinName
+		}
+
+		formatted, errs := format.Source(displayName, src)
+		if errs != nil {
+			for _, err := range errs {
+				fmt.Fprintln(stderr, err)
+			}
+			status = exitError
+			continue
+		}
+
+		if *diff {
+			fmt.Fprint(stdout, unifiedDiff(displayName, src, formatted))
+		}
+		if *write && formatted != src {
+			if err := writeFile(name, formatted); err != nil {
+				fmt.Fprintf(stderr, "monkey: %s\n", err)
+				return exitInternal
+			}
+		}
+		if !*diff && !*write {
+			fmt.Fprint(stdout, formatted)
+		}
+	}
+
+	return status
+}
+
+// Replaces the content of the file name, keeping its permissions.
+func writeFile(name, content string) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, []byte(content), info.Mode().Perm())
+}
@@ -0,0 +1,39 @@
+// Package format prints Monkey programs in their canonical layout: tabs
+// for indentation, one statement per line, single spaces around binary
+// operators and only the parentheses the grouping needs.
+package format
+
+import (
+	"github.com/jolisper/monkey/ast"
+	"github.com/jolisper/monkey/lexer"
+	"github.com/jolisper/monkey/parser"
+)
+
+// Source formats the program src, read from filename. Comments are kept,
+// and so are single blank lines between statements. The result is stable:
+// formatting it again gives it back unchanged.
+//
+// When src does not parse, Source returns the parser errors instead.
+func Source(filename, src string) (string, []*parser.ParseError) {
+	l := lexer.NewWithFilename(filename, src)
+	l.EmitComments(true)
+
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return "", p.Errors()
+	}
+
+	pr := &printer{src: src, comments: p.Comments()}
+	pr.program(program)
+	return pr.out.String(), nil
+}
+
+// Node formats a node of an AST that does not come with its source, like
+// one built or rewritten by a tool. A program ends with a newline, other
+// nodes do not.
+func Node(node ast.Node) string {
+	pr := &printer{}
+	pr.node(node)
+	return pr.out.String()
+}
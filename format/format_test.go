@@ -0,0 +1,174 @@
+package format_test
+
+import (
+	"testing"
+
+	"github.com/jolisper/monkey/ast"
+	"github.com/jolisper/monkey/format"
+	"github.com/jolisper/monkey/lexer"
+	"github.com/jolisper/monkey/parser"
+	"github.com/jolisper/monkey/token"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"let   x=5 ;let y = x", "let x = 5;\nlet y = x;\n"},
+		{"const c = 1", "const c = 1;\n"},
+		{"return  1", "return 1;\n"},
+		{"1+2*3; (1 + 2) * 3", "1 + 2 * 3;\n(1 + 2) * 3;\n"},
+		{"a - (b - c); (a - b) - c", "a - (b - c);\na - b - c;\n"},
+		{"2 ** (3 ** 2); (2 ** 3) ** 2", "2 ** 3 ** 2;\n(2 ** 3) ** 2;\n"},
+		{"(-2) ** 2; -(2 ** 2); -(-x); !(a == b)", "-2 ** 2;\n-(2 ** 2);\n--x;\n!(a == b);\n"},
+		{"a && (b || c); (a && b) || c", "a && (b || c);\na && b || c;\n"},
+		{"x = (y = 1); x += (a = 2)", "x = y = 1;\nx += a = 2;\n"},
+		{"(-f)(1); (a + b)[0]; f(1)(2)[3]", "(-f)(1);\n(a + b)[0];\nf(1)(2)[3];\n"},
+		{`["a\"b\\", "\n\t\u{1}é"]`, `["a\"b\\", "\n\t\u{1}é"];` + "\n"},
+		{"{}; {1:2,  true : [ ]}", "{};\n{1: 2, true: []};\n"},
+		{"1.50; 123456789012345678901234567890", "1.50;\n123456789012345678901234567890;\n"},
+		{"let f = fn(a,b){a+b};", "let f = fn(a, b) { a + b };\n"},
+		{"let f = fn() { };", "let f = fn() {};\n"},
+		{"let m = macro(a,b){quote(unquote(a)+unquote(b))};", "let m = macro(a, b) { quote(unquote(a) + unquote(b)) };\n"},
+		{`import  "lib/math" ;math . sqrt(x)`, "import \"lib/math\";\nmath.sqrt(x);\n"},
+		{"(-a).b; (a + b).c; a.b.c", "(-a).b;\n(a + b).c;\na.b.c;\n"},
+		{"let f = fn() {\nreturn 1 }", "let f = fn() {\n\treturn 1;\n};\n"},
+		{"fn() { 1; 2 }", "fn() {\n\t1;\n\t2;\n};\n"},
+		{"if (a) { b } else { c }", "if (a) { b } else { c }\n"},
+		{"if (a) {\nb\n}\nc", "if (a) {\n\tb;\n}\nc;\n"},
+		{"if (a) { b };\n-1", "if (a) { b };\n-1;\n"},
+		{"if (a) { b };\n(c)(1)", "if (a) { b }\nc(1);\n"},
+		{"if (a) { b };\n(c + 1) * 2", "if (a) { b };\n(c + 1) * 2;\n"},
+		{"while (i<3) { i += 1; }", "while (i < 3) { i += 1 }\n"},
+		{"for (x in xs) { if (x) { break; } continue; }", "for (x in xs) {\n\tif (x) { break; }\n\tcontinue;\n}\n"},
+		{"let a = 1;\n\n\n\nlet b = 2;", "let a = 1;\n\nlet b = 2;\n"},
+		{"let f = fn() {\n\n  1;\n\n  2;\n\n};", "let f = fn() {\n\t1;\n\n\t2;\n};\n"},
+		{"let h = {\n\"a\": 1,\n\n  \"b\": 2,\n};", "let h = {\n\t\"a\": 1,\n\n\t\"b\": 2\n};\n"},
+		{"f(\n1, [\n2])", "f(\n\t1,\n\t[\n\t\t2\n\t]\n);\n"},
+		{"let f = fn(x) {\nlet g = fn() { if (x) { x } };\n}", "let f = fn(x) {\n\tlet g = fn() { if (x) { x } };\n};\n"},
+	}
+
+	for _, tt := range tests {
+		formatted, errs := format.Source("test.mk", tt.input)
+		if errs != nil {
+			t.Errorf("parser errors for %q: %v", tt.input, errs)
+			continue
+		}
+
+		if formatted != tt.expected {
+			t.Errorf("wrong result for %q.\nwant=%q\ngot= %q", tt.input, tt.expected, formatted)
+		}
+
+		testIdempotent(t, formatted)
+		testSameProgram(t, tt.input, formatted)
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"// only", "// only\n"},
+		{"// header\n\nlet a = 1;", "// header\n\nlet a = 1;\n"},
+		{"let a = 1;   // one\nlet b = 2; /* two */ let c = 3;", "let a = 1; // one\nlet b = 2; /* two */\nlet c = 3;\n"},
+		{"/* a\n   b */\nx", "/* a\n   b */\nx;\n"},
+		{"let f = fn() { // why\n  1;\n   // end\n};", "let f = fn() { // why\n\t1;\n\t// end\n};\n"},
+		{"if (a) { /* empty */ }", "if (a) { /* empty */\n}\n"},
+		{"let h = {\n  // first\n  \"a\": 1, // one\n  \"b\": 2\n};", "let h = {\n\t// first\n\t\"a\": 1, // one\n\t\"b\": 2\n};\n"},
+		{"f(a, /* b */ c); // end\n\n// footer", "f(a, /* b */ c); // end\n\n// footer\n"},
+		// comments inside an expression stay next to the code they follow or precede
+		{"let f = fn(a /* param */, b) { a };", "let f = fn(a /* param */, b) { a };\n"},
+		{"let f = fn(/* none */) { 1 }; fn(a) /* x */ { a }", "let f = fn(/* none */) { 1 };\nfn(a) /* x */ { a };\n"},
+		{"f(1 /* one */, /* two */ 2 /* end */)", "f(1 /* one */, /* two */ 2 /* end */);\n"},
+		{"[1, 2 /* two */, 3];", "[1, 2 /* two */, 3];\n"},
+		{`{"a": 1,/* end */};`, `{"a": 1 /* end */};` + "\n"},
+		{"[/* first */ 1, (2 /* in */) /* out */];", "[/* first */ 1, 2 /* in */ /* out */];\n"},
+		{`{"a" /* key */: 1, "b": /* value */ 2};`, `{"a" /* key */: 1, "b": /* value */ 2};` + "\n"},
+		{"x[/* i */ 0] /* after */;", "x[/* i */ 0] /* after */;\n"},
+		{"if (/* c */ a) /* t */ { b } /* e */ else { c }", "if (/* c */ a) /* t */ { b } /* e */ else { c }\n"},
+		{"f(a, // why\nb);", "f(\n\ta, // why\n\tb\n);\n"},
+		{"[1,\n 2 // two\n];", "[\n\t1,\n\t2 // two\n];\n"},
+	}
+
+	for _, tt := range tests {
+		formatted, errs := format.Source("test.mk", tt.input)
+		if errs != nil {
+			t.Errorf("parser errors for %q: %v", tt.input, errs)
+			continue
+		}
+
+		if formatted != tt.expected {
+			t.Errorf("wrong result for %q.\nwant=%q\ngot= %q", tt.input, tt.expected, formatted)
+		}
+
+		testIdempotent(t, formatted)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, errs := format.Source("test.mk", "let = 1;")
+	if len(errs) != 1 {
+		t.Fatalf("wrong number of errors. want=1, got=%d (%v)", len(errs), errs)
+	}
+
+	expected := "test.mk:1:5: expected next token to be IDENT, got = instead"
+	if errs[0].Error() != expected {
+		t.Errorf("wrong error. want=%q, got=%q", expected, errs[0].Error())
+	}
+}
+
+func TestNode(t *testing.T) {
+	// a tree built by hand, without positions or source
+	node := &ast.InfixExpression{
+		Token:    token.Token{Type: token.ASTERISK, Literal: "*"},
+		Operator: "*",
+		Left: &ast.InfixExpression{
+			Token:    token.Token{Type: token.PLUS, Literal: "+"},
+			Operator: "+",
+			Left:     &ast.IntegerLiteral{Value: 1},
+			Right:    &ast.Identifier{Value: "x"},
+		},
+		Right: &ast.CallExpression{
+			Function: &ast.FunctionLiteral{
+				Body: &ast.BlockStatement{Statements: []ast.Statement{
+					&ast.ReturnStatement{ReturnValue: &ast.StringLiteral{Value: "s"}},
+				}},
+			},
+		},
+	}
+
+	expected := `(1 + x) * fn() { return "s"; }()`
+	if formatted := format.Node(node); formatted != expected {
+		t.Errorf("wrong result. want=%q, got=%q", expected, formatted)
+	}
+}
+
+func testIdempotent(t *testing.T, formatted string) {
+	t.Helper()
+
+	again, errs := format.Source("test.mk", formatted)
+	if errs != nil {
+		t.Errorf("formatted program does not parse: %v\n%s", errs, formatted)
+		return
+	}
+	if again != formatted {
+		t.Errorf("formatting is not stable.\nfirst= %q\nsecond=%q", formatted, again)
+	}
+}
+
+// Checks that input and formatted parse to the same tree, which String
+// renders with all its parentheses.
+func testSameProgram(t *testing.T, input, formatted string) {
+	t.Helper()
+
+	if parse(input) != parse(formatted) {
+		t.Errorf("formatting changed the program.\ninput=    %q\nformatted=%q", parse(input), parse(formatted))
+	}
+}
+
+func parse(input string) string {
+	return parser.New(lexer.New(input)).ParseProgram().String()
+}
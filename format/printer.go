@@ -0,0 +1,577 @@
+package format
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jolisper/monkey/ast"
+	"github.com/jolisper/monkey/parser"
+	"github.com/jolisper/monkey/token"
+)
+
+type printer struct {
+	src      string        // the source of the tree, empty when unknown
+	comments []token.Token // comments not printed yet, in source order
+	out      strings.Builder
+	indent   int
+
+	// Set after an opening brace or bracket, until the first item on the
+	// lines that follow it. No blank line is kept there.
+	afterOpen bool
+}
+
+func (p *printer) node(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Program:
+		p.program(node)
+	case ast.Statement:
+		p.statement(node, true)
+	case ast.Expression:
+		p.expression(node)
+	}
+}
+
+func (p *printer) program(program *ast.Program) {
+	p.statements(program.Statements, math.MaxInt)
+	if p.out.Len() > 0 {
+		p.out.WriteByte('\n')
+	}
+}
+
+// Prints one statement per line, then the comments found before the end
+// offset.
+func (p *printer) statements(stmts []ast.Statement, end int) {
+	for i, s := range stmts {
+		p.flushComments(s.Pos().Offset)
+		p.beginItem(s.Pos().Offset)
+
+		terminate := true
+		if es, ok := s.(*ast.ExpressionStatement); ok {
+			var next ast.Statement
+			if i+1 < len(stmts) {
+				next = stmts[i+1]
+			}
+			terminate = needsSemicolon(es, next)
+		}
+		p.statement(s, terminate)
+	}
+	p.flushComments(end)
+}
+
+// Reports whether the expression statement s must end with a semicolon.
+// The one after an if expression is left out, unless the next statement
+// starts with a token that would otherwise continue the if expression,
+// like the - of -1.
+func needsSemicolon(s *ast.ExpressionStatement, next ast.Statement) bool {
+	if _, ok := ast.Unparen(s.Expression).(*ast.IfExpression); !ok {
+		return true
+	}
+	es, ok := next.(*ast.ExpressionStatement)
+	return ok && startsWithOperator(es.Expression)
+}
+
+// Prints a statement. terminate only matters for expression statements,
+// every other statement ends with a semicolon unless it ends with a block.
+func (p *printer) statement(s ast.Statement, terminate bool) {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		if s.IsConst() {
+			p.out.WriteString("const ")
+		} else {
+			p.out.WriteString("let ")
+		}
+		p.out.WriteString(s.Name.Value + " = ")
+		p.expression(s.Value)
+		p.out.WriteString(";")
+
+	case *ast.ReturnStatement:
+		p.out.WriteString("return ")
+		p.expression(s.ReturnValue)
+		p.out.WriteString(";")
+
+	case *ast.ExpressionStatement:
+		p.expression(s.Expression)
+		if terminate {
+			p.out.WriteString(";")
+		}
+
+	case *ast.WhileStatement:
+		p.out.WriteString("while (")
+		p.expression(s.Condition)
+		p.out.WriteString(") ")
+		p.block(s.Body)
+
+	case *ast.ForStatement:
+		p.out.WriteString("for (" + s.Variable.Value + " in ")
+		p.expression(s.Iterable)
+		p.out.WriteString(") ")
+		p.block(s.Body)
+
+	case *ast.ImportStatement:
+		p.out.WriteString("import " + quote(s.Path.Value) + ";")
+
+	case *ast.BreakStatement:
+		p.out.WriteString("break;")
+
+	case *ast.ContinueStatement:
+		p.out.WriteString("continue;")
+
+	case *ast.BlockStatement:
+		p.block(s)
+	}
+}
+
+// Prints a block over several lines, or on a single line when it is empty
+// or holds a single statement written on one line with its braces. Like
+// expression, it keeps the block comments right before and after it.
+func (p *printer) block(b *ast.BlockStatement) {
+	p.commentsBefore(b.Token.Pos.Offset)
+
+	if text, ok := p.inlineBlock(b); ok {
+		p.out.WriteString(text)
+	} else {
+		p.out.WriteString("{")
+		p.indent++
+		p.afterOpen = true
+		p.statements(b.Statements, b.Rbrace.Offset)
+		p.indent--
+		p.newline()
+		p.out.WriteString("}")
+	}
+
+	p.commentsAfter(b.End())
+}
+
+func (p *printer) inlineBlock(b *ast.BlockStatement) (string, bool) {
+	if p.hasComments(b.Token.Pos.Offset, b.Rbrace.Offset) {
+		return "", false
+	}
+	if len(b.Statements) == 0 {
+		return "{}", true
+	}
+	if len(b.Statements) > 1 || b.Token.Pos.Line != b.Rbrace.Line {
+		return "", false
+	}
+
+	inner := &printer{src: p.src, indent: p.indent}
+	inner.statement(b.Statements[0], false)
+
+	text := inner.out.String()
+	if strings.Contains(text, "\n") {
+		return "", false
+	}
+	return "{ " + text + " }", true
+}
+
+// Prints e, leaving out the parentheses of the source: operands get the
+// ones their precedence needs. The block comments right before and after
+// e in the source stay there.
+func (p *printer) expression(e ast.Expression) {
+	inner := ast.Unparen(e)
+
+	p.commentsBefore(inner.Pos().Offset)
+	p.bareExpression(inner)
+	p.commentsAfter(inner.End())
+	p.commentsAfter(e.End())
+}
+
+func (p *printer) bareExpression(e ast.Expression) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.out.WriteString(e.Value)
+
+	case *ast.IntegerLiteral:
+		switch {
+		case e.Token.Literal != "":
+			p.out.WriteString(e.Token.Literal)
+		case e.Big != nil:
+			p.out.WriteString(e.Big.String())
+		default:
+			p.out.WriteString(strconv.FormatInt(e.Value, 10))
+		}
+
+	case *ast.FloatLiteral:
+		if e.Token.Literal != "" {
+			p.out.WriteString(e.Token.Literal)
+		} else {
+			p.out.WriteString(strconv.FormatFloat(e.Value, 'f', -1, 64))
+		}
+
+	case *ast.StringLiteral:
+		p.out.WriteString(quote(e.Value))
+
+	case *ast.Boolean:
+		p.out.WriteString(strconv.FormatBool(e.Value))
+
+	case *ast.PrefixExpression:
+		p.out.WriteString(e.Operator)
+		p.operand(e.Right, precedence(e.Right) < parser.PREFIX)
+
+	case *ast.InfixExpression:
+		p.binary(e.Token.Type, e.Left, e.Operator, e.Right)
+
+	case *ast.LogicalExpression:
+		p.binary(e.Token.Type, e.Left, e.Operator, e.Right)
+
+	case *ast.AssignExpression:
+		p.binary(e.Token.Type, e.Target, e.Operator, e.Value)
+
+	case *ast.IfExpression:
+		p.out.WriteString("if (")
+		p.expression(e.Condition)
+		p.out.WriteString(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.out.WriteString(" else ")
+			p.block(e.Alternative)
+		}
+
+	case *ast.FunctionLiteral:
+		p.out.WriteString("fn")
+		p.parameters(e.Parameters, e.Rparen)
+		p.block(e.Body)
+
+	case *ast.MacroLiteral:
+		p.out.WriteString("macro")
+		p.parameters(e.Parameters, e.Rparen)
+		p.block(e.Body)
+
+	case *ast.CallExpression:
+		p.operand(e.Function, precedence(e.Function) < parser.CALL)
+		p.list("(", ")", e.Token.Pos, e.Rparen, positions(e.Arguments), func(i int) {
+			p.expression(e.Arguments[i])
+		})
+
+	case *ast.IndexExpression:
+		p.operand(e.Left, precedence(e.Left) < parser.CALL)
+		p.out.WriteString("[")
+		p.expression(e.Index)
+		p.out.WriteString("]")
+
+	case *ast.MemberExpression:
+		p.operand(e.Left, precedence(e.Left) < parser.CALL)
+		p.out.WriteString("." + e.Member.Value)
+
+	case *ast.ArrayLiteral:
+		p.list("[", "]", e.Token.Pos, e.Rbrack, positions(e.Elements), func(i int) {
+			p.expression(e.Elements[i])
+		})
+
+	case *ast.HashLiteral:
+		keys := make([]ast.Expression, len(e.Pairs))
+		for i, pair := range e.Pairs {
+			keys[i] = pair.Key
+		}
+		p.list("{", "}", e.Token.Pos, e.Rbrace, positions(keys), func(i int) {
+			p.expression(e.Pairs[i].Key)
+			p.out.WriteString(": ")
+			p.expression(e.Pairs[i].Value)
+		})
+	}
+}
+
+func (p *printer) binary(op token.TokenType, left ast.Expression, operator string, right ast.Expression) {
+	p.operand(left, needsParens(op, left, true))
+	p.out.WriteString(" " + operator + " ")
+	p.operand(right, needsParens(op, right, false))
+}
+
+func (p *printer) operand(e ast.Expression, parens bool) {
+	if parens {
+		p.out.WriteString("(")
+	}
+	p.expression(e)
+	if parens {
+		p.out.WriteString(")")
+	}
+}
+
+// Prints the parameters of a function or macro literal, in parentheses.
+func (p *printer) parameters(params []*ast.Identifier, rparen token.Position) {
+	p.out.WriteString("(")
+	for i, param := range params {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		p.expression(param)
+	}
+	p.commentsBeforeClose(rparen.Offset)
+	p.out.WriteString(") ")
+}
+
+// Prints the items of a list between open and close, separated by commas.
+// The list is broken into one item per line when its first item starts on
+// a line after the opening bracket, as it did in the source, or when a
+// line comment, which ends its line, is inside.
+func (p *printer) list(open, close string, openPos, closePos token.Position, items []token.Position, item func(i int)) {
+	p.out.WriteString(open)
+
+	multiline := len(items) > 0 && items[0].Line > openPos.Line ||
+		p.hasLineComments(openPos.Offset, closePos.Offset)
+	if multiline {
+		p.indent++
+		p.afterOpen = true
+	}
+
+	for i, pos := range items {
+		if multiline {
+			p.flushComments(pos.Offset)
+			p.beginItem(pos.Offset)
+		} else if i > 0 {
+			p.out.WriteString(", ")
+		}
+
+		item(i)
+
+		if multiline && i < len(items)-1 {
+			p.out.WriteString(",")
+		}
+	}
+
+	if multiline {
+		p.flushComments(closePos.Offset)
+		p.indent--
+		p.newline()
+	} else {
+		p.commentsBeforeClose(closePos.Offset)
+	}
+	p.out.WriteString(close)
+}
+
+func positions(exps []ast.Expression) []token.Position {
+	pos := make([]token.Position, len(exps))
+	for i, e := range exps {
+		pos[i] = e.Pos()
+	}
+	return pos
+}
+
+// Operators and parentheses:
+
+// Returns how tightly e holds together as an operand, using the parser
+// precedences. Anything but an operator expression never needs
+// parentheses.
+func precedence(e ast.Expression) int {
+	switch e := ast.Unparen(e).(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.LogicalExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.AssignExpression:
+		return parser.ASSIGN
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	}
+	return parser.INDEX
+}
+
+// Reports whether operand, on the left or right side of the binary
+// operator op, must be put in parentheses to parse back the same way.
+func needsParens(op token.TokenType, operand ast.Expression, left bool) bool {
+	opPrecedence := parser.Precedence(op)
+	if prec := precedence(operand); prec != opPrecedence {
+		return prec < opPrecedence
+	}
+	return left == parser.RightAssociative(op)
+}
+
+// Reports whether the printed form of e starts with -, ( or [, the tokens
+// that can both start an expression and continue one.
+func startsWithOperator(e ast.Expression) bool {
+	switch e := ast.Unparen(e).(type) {
+	case *ast.PrefixExpression:
+		return e.Operator == "-"
+	case *ast.ArrayLiteral:
+		return true
+	case *ast.InfixExpression:
+		return needsParens(e.Token.Type, e.Left, true) || startsWithOperator(e.Left)
+	case *ast.LogicalExpression:
+		return needsParens(e.Token.Type, e.Left, true) || startsWithOperator(e.Left)
+	case *ast.AssignExpression:
+		return startsWithOperator(e.Target)
+	case *ast.CallExpression:
+		return precedence(e.Function) < parser.CALL || startsWithOperator(e.Function)
+	case *ast.IndexExpression:
+		return precedence(e.Left) < parser.CALL || startsWithOperator(e.Left)
+	case *ast.MemberExpression:
+		return precedence(e.Left) < parser.CALL || startsWithOperator(e.Left)
+	}
+	return false
+}
+
+// Layout:
+
+func (p *printer) newline() {
+	p.out.WriteByte('\n')
+	p.out.WriteString(strings.Repeat("\t", p.indent))
+}
+
+// Starts the line of an item of a list, a statement, a comment or an
+// element, which starts at offset in the source. A blank line before it in
+// the source is kept, but never at the start of a list.
+func (p *printer) beginItem(offset int) {
+	if p.out.Len() > 0 {
+		if !p.afterOpen && p.blankLineBefore(offset) {
+			p.out.WriteByte('\n')
+		}
+		p.newline()
+	}
+	p.afterOpen = false
+}
+
+// Reports whether the source has an empty line right before offset.
+func (p *printer) blankLineBefore(offset int) bool {
+	newlines := 0
+	for i := offset - 1; i >= 0 && i < len(p.src); i-- {
+		switch p.src[i] {
+		case '\n':
+			newlines++
+			if newlines == 2 {
+				return true
+			}
+		case ' ', '\t', '\r':
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// Comments:
+
+// Prints the comments that start before offset. A comment following code
+// on its line in the source stays at the end of the current line, the
+// others get lines of their own.
+func (p *printer) flushComments(offset int) {
+	for len(p.comments) > 0 && p.comments[0].Pos.Offset < offset {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if p.followsCode(c) && p.lineHasContent() {
+			p.out.WriteString(" " + c.Literal)
+			continue
+		}
+
+		p.beginItem(c.Pos.Offset)
+		p.out.WriteString(c.Literal)
+	}
+}
+
+// Prints the block comments that start before offset on the current line,
+// where the code at offset goes. A line comment ends its line: it stops
+// them, to be printed by flushComments.
+func (p *printer) commentsBefore(offset int) {
+	for len(p.comments) > 0 && p.comments[0].Pos.Offset < offset && isBlockComment(p.comments[0]) {
+		p.inlineComment()
+		p.out.WriteString(" ")
+	}
+}
+
+// Like commentsBefore, for the comments before a closing bracket.
+func (p *printer) commentsBeforeClose(offset int) {
+	for len(p.comments) > 0 && p.comments[0].Pos.Offset < offset && isBlockComment(p.comments[0]) {
+		p.inlineComment()
+	}
+}
+
+// Prints the block comments that follow the code ending at end with only
+// blanks in between, on the same line, right after that code.
+func (p *printer) commentsAfter(end token.Position) {
+	offset := end.Offset
+	for len(p.comments) > 0 && isBlockComment(p.comments[0]) && end.IsValid() {
+		c := p.comments[0]
+		if c.Pos.Offset < offset || strings.Trim(p.src[offset:c.Pos.Offset], " \t") != "" {
+			return
+		}
+		p.inlineComment()
+		offset = c.End.Offset
+	}
+}
+
+// Prints the next comment on the current line, after a space unless it
+// starts the line or follows an opening bracket.
+func (p *printer) inlineComment() {
+	c := p.comments[0]
+	p.comments = p.comments[1:]
+
+	out := p.out.String()
+	if p.lineHasContent() && !strings.ContainsAny(out[len(out)-1:], " ([{") {
+		p.out.WriteString(" ")
+	}
+	p.out.WriteString(c.Literal)
+}
+
+func isBlockComment(c token.Token) bool {
+	return strings.HasPrefix(c.Literal, "/*")
+}
+
+// Reports whether a comment not printed yet starts between the offsets
+// start and end.
+func (p *printer) hasComments(start, end int) bool {
+	for _, c := range p.comments {
+		if c.Pos.Offset > start && c.Pos.Offset < end {
+			return true
+		}
+	}
+	return false
+}
+
+// Like hasComments, for line comments only.
+func (p *printer) hasLineComments(start, end int) bool {
+	for _, c := range p.comments {
+		if c.Pos.Offset > start && c.Pos.Offset < end && !isBlockComment(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether something precedes the comment c on its line.
+func (p *printer) followsCode(c token.Token) bool {
+	i := c.Pos.Offset - 1
+	for i >= 0 && (p.src[i] == ' ' || p.src[i] == '\t') {
+		i--
+	}
+	return i >= 0 && p.src[i] != '\n'
+}
+
+// Reports whether the current output line has more than indentation.
+func (p *printer) lineHasContent() bool {
+	out := p.out.String()
+	i := len(out) - 1
+	for i >= 0 && out[i] == '\t' {
+		i--
+	}
+	return i >= 0 && out[i] != '\n'
+}
+
+// quote returns s as a string literal, escaping what the lexer unescapes.
+func quote(s string) string {
+	var out strings.Builder
+
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if unicode.IsControl(r) {
+				fmt.Fprintf(&out, `\u{%x}`, r)
+			} else {
+				out.WriteRune(r)
+			}
+		}
+	}
+	out.WriteByte('"')
+
+	return out.String()
+}
@@ -1,20 +1,47 @@
 package lexer
 
-import "github.com/jolisper/monkey/token"
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/jolisper/monkey/token"
+)
+
+// Lexer turns UTF-8 encoded source into tokens. Offsets in token positions
+// count bytes while columns count characters.
 type Lexer struct {
+	filename     string
 	input        string
 	position     int  // points to current char
 	readPosition int  // after current char
-	ch           byte // current char
+	ch           rune // current char
+	width        int  // size in bytes of current char
+	line         int  // line of current char
+	column       int  // column of current char
+
+	emitComments bool
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewWithFilename("", input)
+}
+
+// NewWithFilename returns a lexer whose token positions are reported as
+// belonging to filename.
+func NewWithFilename(filename, input string) *Lexer {
+	l := &Lexer{filename: filename, input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// EmitComments makes the lexer return comments as COMMENT tokens instead of
+// skipping them, for tools that need to keep them around, like a formatter.
+func (l *Lexer) EmitComments(emit bool) {
+	l.emitComments = emit
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
@@ -22,42 +49,87 @@ func (l *Lexer) skipWhitespace() {
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
 	if l.readPosition >= len(l.input) {
-		l.ch = 0
+		l.ch, l.width = 0, 1
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, l.width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += l.width
+	l.column += 1
 }
 
-func (l *Lexer) peekChar() byte {
+// invalidChar reports whether the current char is a byte that is not part
+// of a valid UTF-8 encoding.
+func (l *Lexer) invalidChar() bool {
+	return l.ch == utf8.RuneError && l.width == 1
+}
+
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.readPosition]
+		r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return r
 	}
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+	for {
+		l.skipWhitespace()
 
-	l.skipWhitespace()
+		pos := l.currentPosition()
+		tok := l.readToken()
+		tok.Pos = pos
+		tok.End = l.currentPosition()
+
+		if tok.Type != token.COMMENT || l.emitComments {
+			return tok
+		}
+	}
+}
+
+// Operators that combine with a following '=' into a compound assignment.
+var compoundAssignments = map[token.TokenType]token.TokenType{
+	token.PLUS:     token.PLUS_ASSIGN,
+	token.MINUS:    token.MINUS_ASSIGN,
+	token.ASTERISK: token.ASTERISK_ASSIGN,
+	token.SLASH:    token.SLASH_ASSIGN,
+	token.PERCENT:  token.PERCENT_ASSIGN,
+	token.POWER:    token.POWER_ASSIGN,
+	token.BIT_AND:  token.BIT_AND_ASSIGN,
+	token.BIT_OR:   token.BIT_OR_ASSIGN,
+	token.BIT_XOR:  token.BIT_XOR_ASSIGN,
+	token.SHL:      token.SHL_ASSIGN,
+	token.SHR:      token.SHR_ASSIGN,
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.EQ, Literal: string(ch) + string(l.ch)}
+			tok = l.readTwoCharToken(token.EQ)
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '!':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.NOT_EQ, Literal: string(ch) + string(l.ch)}
+			tok = l.readTwoCharToken(token.NOT_EQ)
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
@@ -66,13 +138,39 @@ func (l *Lexer) NextToken() token.Token {
 	case '-':
 		tok = newToken(token.MINUS, l.ch)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '*' {
+			tok = l.readTwoCharToken(token.POWER)
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		switch l.peekChar() {
+		case '/':
+			tok.Type = token.COMMENT
+			tok.Literal = l.readLineComment()
+			return tok
+		case '*':
+			literal, ok := l.readBlockComment()
+			if ok {
+				tok.Type = token.COMMENT
+			} else {
+				tok.Type = token.ILLEGAL
+			}
+			tok.Literal = literal
+			return tok
+		default:
+			tok = newToken(token.SLASH, l.ch)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
+	case '.':
+		tok = newToken(token.DOT, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -81,10 +179,52 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.readTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.BIT_AND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.readTwoCharToken(token.OR)
+		} else {
+			tok = newToken(token.BIT_OR, l.ch)
+		}
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
+	case '~':
+		tok = newToken(token.BIT_NOT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.readTwoCharToken(token.LT_EQ)
+		case '<':
+			tok = l.readTwoCharToken(token.SHL)
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.readTwoCharToken(token.GT_EQ)
+		case '>':
+			tok = l.readTwoCharToken(token.SHR)
+		default:
+			tok = newToken(token.GT, l.ch)
+		}
+	case '"':
+		literal, ok := l.readString()
+		if ok {
+			tok.Type = token.STRING
+		} else {
+			tok.Type = token.ILLEGAL
+		}
+		tok.Literal = literal
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -94,45 +234,219 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Literal, tok.Type = l.readNumber()
 			return tok
+		} else if l.invalidChar() {
+			tok.Type = token.ILLEGAL
+			tok.Literal = l.input[l.position:l.readPosition]
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 
 	}
+
+	if assignType, ok := compoundAssignments[tok.Type]; ok && l.peekChar() == '=' {
+		l.readChar()
+		tok = token.Token{Type: assignType, Literal: tok.Literal + "="}
+	}
+
 	l.readChar()
 	return tok
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+// readTwoCharToken consumes the current and the next char as a single
+// token of the given type.
+func (l *Lexer) readTwoCharToken(tokenType token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: tokenType, Literal: string(ch) + string(l.ch)}
+}
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{
 		Type:    tokenType,
 		Literal: string(ch),
 	}
 }
 
+// readLineComment reads a // comment up to, but not including, the end of
+// the line.
+func (l *Lexer) readLineComment() string {
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	return strings.TrimSuffix(l.input[position:l.position], "\r")
+}
+
+// readBlockComment reads a /* */ comment, delimiters included. Block
+// comments nest, so that code containing comments can be commented out.
+// When the input ends before the comment is closed it returns the text read
+// so far and false.
+func (l *Lexer) readBlockComment() (string, bool) {
+	position := l.position
+	depth := 0
+
+	for l.ch != 0 {
+		switch {
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+		}
+		l.readChar()
+
+		if depth == 0 {
+			return l.input[position:l.position], true
+		}
+	}
+
+	return l.input[position:l.position], false
+}
+
+// readIdentifier reads a letter followed by letters and digits.
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// isLetter reports whether ch can start an identifier: any Unicode letter
+// or an underscore.
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer, or a float when the digits are followed by a
+// fractional part (1.5), an exponent (1e10, 2E-3) or both (1.5e+3).
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
+	tokenType := token.TokenType(token.INT)
+
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		offset := 1
+		if sign := l.peekCharAt(offset); sign == '+' || sign == '-' {
+			offset++
+		}
+
+		if isDigit(l.peekCharAt(offset)) {
+			tokenType = token.FLOAT
+			for i := 0; i < offset; i++ {
+				l.readChar()
+			}
+			l.readDigits()
+		}
+	}
+
+	return l.input[position:l.position], tokenType
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
 }
 
-func isDigit(ch byte) bool {
+// peekCharAt returns the byte n bytes after the current char, which is the
+// char n positions after it as long as the chars in between are ASCII.
+func (l *Lexer) peekCharAt(n int) rune {
+	if l.position+n >= len(l.input) {
+		return 0
+	}
+	return rune(l.input[l.position+n])
+}
+
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+// readString reads a double quoted string starting at the opening quote and
+// returns its contents with escape sequences resolved. The lexer is left on
+// the closing quote. On an unterminated string, an invalid escape sequence
+// or invalid UTF-8 it returns the raw source text of the string and false.
+func (l *Lexer) readString() (string, bool) {
+	start := l.position
+	valid := true
+	var out strings.Builder
+
+	for {
+		l.readChar()
+
+		switch l.ch {
+		case '"':
+			if !valid {
+				return l.input[start:l.readPosition], false
+			}
+			return out.String(), true
+		case 0:
+			return l.input[start:l.position], false
+		case '\\':
+			switch l.peekChar() {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case '"':
+				out.WriteByte('"')
+			case '\\':
+				out.WriteByte('\\')
+			case 'u':
+				l.readChar()
+				r, ok := l.readUnicodeEscape()
+				valid = valid && ok
+				out.WriteRune(r)
+				continue
+			default:
+				valid = false
+				continue
+			}
+			l.readChar()
+		default:
+			valid = valid && !l.invalidChar()
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// readUnicodeEscape reads the {XXXX} part of a \u{XXXX} escape sequence,
+// leaving the lexer on the closing brace.
+func (l *Lexer) readUnicodeEscape() (rune, bool) {
+	if l.peekChar() != '{' {
+		return 0, false
+	}
+	l.readChar()
+
+	position := l.position + 1
+	for isHexDigit(l.peekChar()) {
+		l.readChar()
+	}
+	digits := l.input[position:l.readPosition]
+
+	if l.peekChar() != '}' || len(digits) == 0 || len(digits) > 6 {
+		return 0, false
+	}
+	l.readChar()
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || !utf8.ValidRune(rune(value)) {
+		return 0, false
+	}
+
+	return rune(value), true
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
@@ -17,7 +17,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -28,6 +28,7 @@ if (5 < 10) {
 
 10 == 10;
 10 != 9;
+[1, 2];
 `
 
 	tests := []struct {
@@ -116,6 +117,13 @@ if (5 < 10) {
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
 
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+
 		{token.EOF, ""},
 	}
 
@@ -131,3 +139,272 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x == 10;"
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedPos    token.Position
+		expectedEndCol int
+	}{
+		{token.LET, token.Position{Filename: "test.mk", Offset: 0, Line: 1, Column: 1}, 4},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 4, Line: 1, Column: 5}, 6},
+		{token.ASSIGN, token.Position{Filename: "test.mk", Offset: 6, Line: 1, Column: 7}, 8},
+		{token.INT, token.Position{Filename: "test.mk", Offset: 8, Line: 1, Column: 9}, 10},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Offset: 9, Line: 1, Column: 10}, 11},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 13, Line: 2, Column: 3}, 4},
+		{token.EQ, token.Position{Filename: "test.mk", Offset: 15, Line: 2, Column: 5}, 7},
+		{token.INT, token.Position{Filename: "test.mk", Offset: 18, Line: 2, Column: 8}, 10},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Offset: 20, Line: 2, Column: 10}, 11},
+	}
+
+	l := lexer.NewWithFilename("test.mk", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%+v, got=%+v", i, tt.expectedPos, tok.Pos)
+		}
+		if tok.End.Column != tt.expectedEndCol {
+			t.Fatalf("tests[%d] - end column wrong. expected=%d, got=%d", i, tt.expectedEndCol, tok.End.Column)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"foobar"`, token.STRING, "foobar"},
+		{`"foo bar"`, token.STRING, "foo bar"},
+		{`""`, token.STRING, ""},
+		{`"a\nb\tc"`, token.STRING, "a\nb\tc"},
+		{`"say \"hi\""`, token.STRING, `say "hi"`},
+		{`"back\\slash"`, token.STRING, `back\slash`},
+		{`"\u{48}\u{e9}\u{1F600}"`, token.STRING, "H\u00e9\U0001F600"},
+		{`"unterminated`, token.ILLEGAL, `"unterminated`},
+		{`"bad \q escape"`, token.ILLEGAL, `"bad \q escape"`},
+		{`"\u{110000}"`, token.ILLEGAL, `"\u{110000}"`},
+		{`"\u{}"`, token.ILLEGAL, `"\u{}"`},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF after string, got=%q", i, next.Type)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"// only a comment", []token.Token{}},
+		{"1 // one\n2", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.INT, Literal: "2"}}},
+		{"1 /* one */ + 2", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.PLUS, Literal: "+"}, {Type: token.INT, Literal: "2"}}},
+		{"/* outer /* inner */ still outer */ 1", []token.Token{{Type: token.INT, Literal: "1"}}},
+		{"/**/1", []token.Token{{Type: token.INT, Literal: "1"}}},
+		{"1 / 2", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.SLASH, Literal: "/"}, {Type: token.INT, Literal: "2"}}},
+		{`"// not a comment"`, []token.Token{{Type: token.STRING, Literal: "// not a comment"}}},
+		{"1 /* open /* nested */", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.ILLEGAL, Literal: "/* open /* nested */"}}},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+
+		for j, expected := range append(tt.expected, token.Token{Type: token.EOF}) {
+			tok := l.NextToken()
+			if tok.Type != expected.Type {
+				t.Fatalf("tests[%d][%d] - tokentype wrong. expected=%q, got=%q", i, j, expected.Type, tok.Type)
+			}
+			if tok.Literal != expected.Literal {
+				t.Fatalf("tests[%d][%d] - literal wrong. expected=%q, got=%q", i, j, expected.Literal, tok.Literal)
+			}
+		}
+	}
+}
+
+func TestEmitComments(t *testing.T) {
+	input := "// header\nlet x = 1; /* a\nb */ x"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{token.COMMENT, "// header", token.Position{Offset: 0, Line: 1, Column: 1}},
+		{token.LET, "let", token.Position{Offset: 10, Line: 2, Column: 1}},
+		{token.IDENT, "x", token.Position{Offset: 14, Line: 2, Column: 5}},
+		{token.ASSIGN, "=", token.Position{Offset: 16, Line: 2, Column: 7}},
+		{token.INT, "1", token.Position{Offset: 18, Line: 2, Column: 9}},
+		{token.SEMICOLON, ";", token.Position{Offset: 19, Line: 2, Column: 10}},
+		{token.COMMENT, "/* a\nb */", token.Position{Offset: 21, Line: 2, Column: 12}},
+		{token.IDENT, "x", token.Position{Offset: 31, Line: 3, Column: 6}},
+		{token.EOF, "", token.Position{Offset: 32, Line: 3, Column: 7}},
+	}
+
+	l := lexer.New(input)
+	l.EmitComments(true)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%+v, got=%+v", i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"x2", []token.Token{{Type: token.IDENT, Literal: "x2"}}},
+		{"_tmp_1", []token.Token{{Type: token.IDENT, Literal: "_tmp_1"}}},
+		{"año", []token.Token{{Type: token.IDENT, Literal: "año"}}},
+		{"π", []token.Token{{Type: token.IDENT, Literal: "π"}}},
+		{"日本語", []token.Token{{Type: token.IDENT, Literal: "日本語"}}},
+		{"x١٢", []token.Token{{Type: token.IDENT, Literal: "x١٢"}}},
+		{"2x", []token.Token{{Type: token.INT, Literal: "2"}, {Type: token.IDENT, Literal: "x"}}},
+		{"letter", []token.Token{{Type: token.IDENT, Literal: "letter"}}},
+		{"a\xffb", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.ILLEGAL, Literal: "\xff"}, {Type: token.IDENT, Literal: "b"}}},
+		{"€", []token.Token{{Type: token.ILLEGAL, Literal: "€"}}},
+		{`"héllo, 世界"`, []token.Token{{Type: token.STRING, Literal: "héllo, 世界"}}},
+		{"\"a\xffb\"", []token.Token{{Type: token.ILLEGAL, Literal: "\"a\xffb\""}}},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+
+		for j, expected := range append(tt.expected, token.Token{Type: token.EOF}) {
+			tok := l.NextToken()
+			if tok.Type != expected.Type {
+				t.Fatalf("tests[%d][%d] - tokentype wrong. expected=%q, got=%q", i, j, expected.Type, tok.Type)
+			}
+			if tok.Literal != expected.Literal {
+				t.Fatalf("tests[%d][%d] - literal wrong. expected=%q, got=%q", i, j, expected.Literal, tok.Literal)
+			}
+		}
+	}
+}
+
+func TestUnicodePositions(t *testing.T) {
+	input := "let año = \"ñ\";\n日 \xff"
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+	}{
+		{token.LET, token.Position{Offset: 0, Line: 1, Column: 1}},
+		{token.IDENT, token.Position{Offset: 4, Line: 1, Column: 5}},
+		{token.ASSIGN, token.Position{Offset: 9, Line: 1, Column: 9}},
+		{token.STRING, token.Position{Offset: 11, Line: 1, Column: 11}},
+		{token.SEMICOLON, token.Position{Offset: 15, Line: 1, Column: 14}},
+		{token.IDENT, token.Position{Offset: 17, Line: 2, Column: 1}},
+		{token.ILLEGAL, token.Position{Offset: 21, Line: 2, Column: 3}},
+		{token.EOF, token.Position{Offset: 22, Line: 2, Column: 4}},
+	}
+
+	l := lexer.New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%+v, got=%+v", i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"42", []token.Token{{Type: token.INT, Literal: "42"}}},
+		{"3.14", []token.Token{{Type: token.FLOAT, Literal: "3.14"}}},
+		{"0.5", []token.Token{{Type: token.FLOAT, Literal: "0.5"}}},
+		{"1e10", []token.Token{{Type: token.FLOAT, Literal: "1e10"}}},
+		{"2.5E-3", []token.Token{{Type: token.FLOAT, Literal: "2.5E-3"}}},
+		{"6e+2", []token.Token{{Type: token.FLOAT, Literal: "6e+2"}}},
+		{"1.", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.DOT, Literal: "."}}},
+		{"1.x", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.DOT, Literal: "."}, {Type: token.IDENT, Literal: "x"}}},
+		{"1e", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.IDENT, Literal: "e"}}},
+		{"1e+", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.IDENT, Literal: "e"}, {Type: token.PLUS, Literal: "+"}}},
+		{"1.5+2", []token.Token{{Type: token.FLOAT, Literal: "1.5"}, {Type: token.PLUS, Literal: "+"}, {Type: token.INT, Literal: "2"}}},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+
+		for j, expected := range append(tt.expected, token.Token{Type: token.EOF}) {
+			tok := l.NextToken()
+			if tok.Type != expected.Type {
+				t.Fatalf("tests[%d][%d] - tokentype wrong. expected=%q, got=%q", i, j, expected.Type, tok.Type)
+			}
+			if tok.Literal != expected.Literal {
+				t.Fatalf("tests[%d][%d] - literal wrong. expected=%q, got=%q", i, j, expected.Literal, tok.Literal)
+			}
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"%", []token.Token{{Type: token.PERCENT, Literal: "%"}}},
+		{"&&", []token.Token{{Type: token.AND, Literal: "&&"}}},
+		{"||", []token.Token{{Type: token.OR, Literal: "||"}}},
+		{"a&&!b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.AND, Literal: "&&"}, {Type: token.BANG, Literal: "!"}, {Type: token.IDENT, Literal: "b"}}},
+		{"|||", []token.Token{{Type: token.OR, Literal: "||"}, {Type: token.BIT_OR, Literal: "|"}}},
+		{"**", []token.Token{{Type: token.POWER, Literal: "**"}}},
+		{"***", []token.Token{{Type: token.POWER, Literal: "**"}, {Type: token.ASTERISK, Literal: "*"}}},
+		{"<= >= < >", []token.Token{{Type: token.LT_EQ, Literal: "<="}, {Type: token.GT_EQ, Literal: ">="}, {Type: token.LT, Literal: "<"}, {Type: token.GT, Literal: ">"}}},
+		{"<<>>", []token.Token{{Type: token.SHL, Literal: "<<"}, {Type: token.SHR, Literal: ">>"}}},
+		{"&|^~", []token.Token{{Type: token.BIT_AND, Literal: "&"}, {Type: token.BIT_OR, Literal: "|"}, {Type: token.BIT_XOR, Literal: "^"}, {Type: token.BIT_NOT, Literal: "~"}}},
+		{"<<=", []token.Token{{Type: token.SHL_ASSIGN, Literal: "<<="}}},
+		{"+= -= *= /= %=", []token.Token{{Type: token.PLUS_ASSIGN, Literal: "+="}, {Type: token.MINUS_ASSIGN, Literal: "-="}, {Type: token.ASTERISK_ASSIGN, Literal: "*="}, {Type: token.SLASH_ASSIGN, Literal: "/="}, {Type: token.PERCENT_ASSIGN, Literal: "%="}}},
+		{"**= &= |= ^= >>=", []token.Token{{Type: token.POWER_ASSIGN, Literal: "**="}, {Type: token.BIT_AND_ASSIGN, Literal: "&="}, {Type: token.BIT_OR_ASSIGN, Literal: "|="}, {Type: token.BIT_XOR_ASSIGN, Literal: "^="}, {Type: token.SHR_ASSIGN, Literal: ">>="}}},
+		{"x+=1", []token.Token{{Type: token.IDENT, Literal: "x"}, {Type: token.PLUS_ASSIGN, Literal: "+="}, {Type: token.INT, Literal: "1"}}},
+		{"<= >= == != &&=", []token.Token{{Type: token.LT_EQ, Literal: "<="}, {Type: token.GT_EQ, Literal: ">="}, {Type: token.EQ, Literal: "=="}, {Type: token.NOT_EQ, Literal: "!="}, {Type: token.AND, Literal: "&&"}, {Type: token.ASSIGN, Literal: "="}}},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+
+		for j, expected := range append(tt.expected, token.Token{Type: token.EOF}) {
+			tok := l.NextToken()
+			if tok.Type != expected.Type {
+				t.Fatalf("tests[%d][%d] - tokentype wrong. expected=%q, got=%q", i, j, expected.Type, tok.Type)
+			}
+			if tok.Literal != expected.Literal {
+				t.Fatalf("tests[%d][%d] - literal wrong. expected=%q, got=%q", i, j, expected.Literal, tok.Literal)
+			}
+		}
+	}
+}
@@ -2,19 +2,90 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"path/filepath"
 
+	"github.com/jolisper/monkey/evaluator"
 	"github.com/jolisper/monkey/repl"
 )
 
+const usage = `Usage:
+
+	monkey [command] [arguments]
+
+The commands are:
+
+	run [-engine=eval|vm] FILE   run a Monkey program, "-" reads it from stdin
+	fmt [-w] [-d] [FILE...]      format Monkey programs, stdin without files
+	ast FILE                     print the syntax tree of a program as JSON
+	repl                         start the interactive interpreter
+
+Without a command, monkey runs the program read from stdin when stdin is
+not a terminal, and starts the interactive interpreter otherwise.
+
+Imported files are looked up next to the importing file, then in the
+directories listed in the MONKEYPATH environment variable.
+`
+
+// Exit codes
+const (
+	exitOK       = 0
+	exitError    = 1 // the program has parser, compiler or runtime errors
+	exitUsage    = 2
+	exitInternal = 3 // the program could not be read
+)
+
 func main() {
+	evaluator.SearchPath = filepath.SplitList(os.Getenv("MONKEYPATH"))
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin *os.File, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		if !isTerminal(stdin) {
+			return runFile([]string{"-"}, stdin, stderr)
+		}
+		return startRepl(stdin, stdout)
+	}
+
+	switch args[0] {
+	case "run":
+		return runFile(args[1:], stdin, stderr)
+	case "fmt":
+		return formatFiles(args[1:], stdin, stdout, stderr)
+	case "ast":
+		return dumpAST(args[1:], stdin, stdout, stderr)
+	case "repl":
+		return startRepl(stdin, stdout)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "monkey: unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+func startRepl(in io.Reader, out io.Writer) int {
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
-	fmt.Printf("Feel free to type in commands\n")
+	fmt.Fprintf(out, "Hello %s! This is the Monkey programming language!\n", user.Username)
+	fmt.Fprintf(out, "Feel free to type in commands\n")
 
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(in, out)
+	return exitOK
+}
+
+// Reports whether f is connected to a terminal rather than to a pipe or a
+// regular file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
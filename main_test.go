@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const unlessMacro = "let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };"
+
+func TestRunProgram(t *testing.T) {
+	tests := []struct {
+		input          string
+		engine         string
+		expectedCode   int
+		expectedStderr string
+	}{
+		{"let a = 1; a + 1;", "eval", exitOK, ""},
+		{"let a = 1; a + 1;", "vm", exitOK, ""},
+		{"let a = 1; a + b;", "eval", exitError, "ERROR: test.mk:1:16: identifier not found: b\n"},
+		{"let a = 1; a + b;", "vm", exitError, "ERROR: test.mk:1:16: identifier not found: b\n"},
+		{"1 + true;", "eval", exitError, "ERROR: test.mk:1:1: type mismatch: INTEGER + BOOLEAN\n"},
+		{"1 + true;", "vm", exitError, "ERROR: test.mk:1:1: type mismatch: INTEGER + BOOLEAN\n"},
+		{"let f = fn(x) {\n  x / 0\n};\nf(1);", "eval", exitError, "ERROR: test.mk:2:3: division by zero\n"},
+		{"let f = fn(x) {\n  x / 0\n};\nf(1);", "vm", exitError, "ERROR: test.mk:2:3: division by zero\n"},
+		{"len(1);", "eval", exitError, "ERROR: test.mk:1:1: argument to `len` not supported, got INTEGER\n"},
+		{"len(1);", "vm", exitError, "ERROR: test.mk:1:1: argument to `len` not supported, got INTEGER\n"},
+		{"let = 1;", "eval", exitError, "test.mk:1:5: expected next token to be IDENT, got = instead\n"},
+		{"let = 1;", "vm", exitError, "test.mk:1:5: expected next token to be IDENT, got = instead\n"},
+		{unlessMacro + "unless(false, 1, 1 + true);", "eval", exitOK, ""},
+		{unlessMacro + "unless(false, 1, 1 + true);", "vm", exitOK, ""},
+		{"let m = macro() { 1 }; m();", "eval", exitError, "ERROR: test.mk:1:24: macro must return a quote, got INTEGER\n"},
+		{"let m = macro() { 1 }; m();", "vm", exitError, "ERROR: test.mk:1:24: macro must return a quote, got INTEGER\n"},
+	}
+
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+
+		code := runProgram("test.mk", tt.input, tt.engine, &stderr)
+		if code != tt.expectedCode {
+			t.Errorf("[%s] wrong exit code for %q. want=%d, got=%d", tt.engine, tt.input, tt.expectedCode, code)
+		}
+
+		if stderr.String() != tt.expectedStderr {
+			t.Errorf("[%s] wrong stderr for %q. want=%q, got=%q", tt.engine, tt.input, tt.expectedStderr, stderr.String())
+		}
+	}
+}
+
+func TestRunFileUsage(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"a.mk", "b.mk"},
+		{"-engine=jit", "a.mk"},
+		{"-unknown", "a.mk"},
+	}
+
+	for _, args := range tests {
+		var stderr bytes.Buffer
+
+		if code := runFile(args, strings.NewReader(""), &stderr); code != exitUsage {
+			t.Errorf("wrong exit code for %v. want=%d, got=%d", args, exitUsage, code)
+		}
+	}
+}
+
+func TestRunFileImportCycle(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	files := map[string]string{
+		"main.mk": "let n = 1;\nimport \"a\";",
+		"a.mk":    `import "main";`,
+		"self.mk": `import "self";`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(path(name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"main.mk", "ERROR: " + path("a.mk") + ":1:8: import cycle: " +
+			path("main.mk") + " -> " + path("a.mk") + " -> " + path("main.mk") + "\n"},
+		{"self.mk", "ERROR: " + path("self.mk") + ":1:8: import cycle: " + path("self.mk") + " -> " + path("self.mk") + "\n"},
+	}
+
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+
+		code := runFile([]string{path(tt.file)}, strings.NewReader(""), &stderr)
+		if code != exitError {
+			t.Errorf("wrong exit code for %s. want=%d, got=%d", tt.file, exitError, code)
+		}
+		if stderr.String() != tt.expected {
+			t.Errorf("wrong stderr for %s.\nwant=%q\ngot= %q", tt.file, tt.expected, stderr.String())
+		}
+	}
+}
+
+func TestRunFileFromStdin(t *testing.T) {
+	var stderr bytes.Buffer
+
+	code := runFile([]string{"-"}, strings.NewReader("1 +"), &stderr)
+	if code != exitError {
+		t.Errorf("wrong exit code. want=%d, got=%d", exitError, code)
+	}
+
+	expected := "<stdin>:1:4: no prefix parse function for EOF found\n"
+	if stderr.String() != expected {
+		t.Errorf("wrong stderr. want=%q, got=%q", expected, stderr.String())
+	}
+}
+
+func TestFormatFiles(t *testing.T) {
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{[]string{}, "let a=1", exitOK, "let a = 1;\n", ""},
+		{[]string{"-"}, "let a = 1;\n", exitOK, "let a = 1;\n", ""},
+		{[]string{"-d"}, "let a = 1;\n", exitOK, "", ""},
+		{[]string{"-d"}, "let a = 1;\nlet b=2;\n", exitOK,
+			"--- <stdin>.orig\n+++ <stdin>\n@@ -1,2 +1,2 @@\n let a = 1;\n-let b=2;\n+let b = 2;\n", ""},
+		{[]string{}, "let = 1", exitError, "", "<stdin>:1:5: expected next token to be IDENT, got = instead\n"},
+		{[]string{"-w"}, "", exitUsage, "", "monkey: cannot use -w with stdin\n\n" + usage},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+
+		code := formatFiles(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+		if code != tt.expectedCode {
+			t.Errorf("wrong exit code for %v %q. want=%d, got=%d", tt.args, tt.stdin, tt.expectedCode, code)
+		}
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf("wrong stdout for %v %q. want=%q, got=%q", tt.args, tt.stdin, tt.expectedStdout, stdout.String())
+		}
+		if stderr.String() != tt.expectedStderr {
+			t.Errorf("wrong stderr for %v %q. want=%q, got=%q", tt.args, tt.stdin, tt.expectedStderr, stderr.String())
+		}
+	}
+}
+
+func TestFormatFilesWrite(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "script.mk")
+	if err := os.WriteFile(name, []byte("puts( 1 )"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := formatFiles([]string{"-w", name}, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+		t.Fatalf("wrong exit code. want=%d, got=%d (%s)", exitOK, code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("unexpected output with -w: %q", stdout.String())
+	}
+
+	content, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "puts(1);\n" {
+		t.Errorf("wrong file content. got=%q", content)
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("file permissions changed. got=%v", info.Mode().Perm())
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nm\nn"
+
+	expected := `--- x.orig
++++ x
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -9,5 +9,5 @@
+ i
+ j
+ k
+-l
+ m
++n
+\ No newline at end of file
+`
+
+	if diff := unifiedDiff("x", old, new); diff != expected {
+		t.Errorf("wrong diff.\nwant=%q\ngot= %q", expected, diff)
+	}
+	if diff := unifiedDiff("x", old, old); diff != "" {
+		t.Errorf("diff of equal texts is not empty. got=%q", diff)
+	}
+}
+
+// Checks on many small inputs that diffLines turns x into y keeping a longest
+// common subsequence of them, as computed by the full table.
+func TestDiffLines(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(3)))
+		}
+		return lines
+	}
+
+	for n := 0; n < 500; n++ {
+		x, y := randomLines(), randomLines()
+
+		var gotX, gotY []string
+		common := 0
+		for _, line := range diffLines(x, y) {
+			if line.a != len(gotX) || line.b != len(gotY) {
+				t.Fatalf("wrong line counts for %q -> %q at %+v", x, y, line)
+			}
+			if line.kind != '+' {
+				gotX = append(gotX, line.text)
+			}
+			if line.kind != '-' {
+				gotY = append(gotY, line.text)
+			}
+			if line.kind == ' ' {
+				common++
+			}
+		}
+
+		if strings.Join(gotX, "") != strings.Join(x, "") || strings.Join(gotY, "") != strings.Join(y, "") {
+			t.Fatalf("edit does not turn %q into %q", x, y)
+		}
+
+		lcs := make([][]int, len(x)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(y)+1)
+		}
+		for i := len(x) - 1; i >= 0; i-- {
+			for j := len(y) - 1; j >= 0; j-- {
+				if x[i] == y[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = maxInt(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		if common != lcs[0][0] {
+			t.Fatalf("edit of %q -> %q keeps %d lines, want=%d", x, y, common, lcs[0][0])
+		}
+	}
+}
+
+func TestDumpAST(t *testing.T) {
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{[]string{"-"}, "a", exitOK, `{
+  "kind": "Program",
+  "start": {
+    "offset": 0,
+    "line": 1,
+    "column": 1
+  },
+  "end": {
+    "offset": 1,
+    "line": 1,
+    "column": 2
+  },
+  "filename": "<stdin>",
+  "statements": [
+    {
+      "kind": "ExpressionStatement",
+      "start": {
+        "offset": 0,
+        "line": 1,
+        "column": 1
+      },
+      "end": {
+        "offset": 1,
+        "line": 1,
+        "column": 2
+      },
+      "expression": {
+        "kind": "Identifier",
+        "start": {
+          "offset": 0,
+          "line": 1,
+          "column": 1
+        },
+        "end": {
+          "offset": 1,
+          "line": 1,
+          "column": 2
+        },
+        "value": "a"
+      }
+    }
+  ]
+}
+`, ""},
+		{[]string{"-"}, "let = 1", exitError, "", "<stdin>:1:5: expected next token to be IDENT, got = instead\n"},
+		{[]string{}, "", exitUsage, "", "monkey: ast expects exactly one file\n\n" + usage},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+
+		code := dumpAST(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+		if code != tt.expectedCode {
+			t.Errorf("wrong exit code for %v %q. want=%d, got=%d", tt.args, tt.stdin, tt.expectedCode, code)
+		}
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf("wrong stdout for %v %q. want=%q, got=%q", tt.args, tt.stdin, tt.expectedStdout, stdout.String())
+		}
+		if stderr.String() != tt.expectedStderr {
+			t.Errorf("wrong stderr for %v %q. want=%q, got=%q", tt.args, tt.stdin, tt.expectedStderr, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package object
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// BuiltinDefinition binds a builtin function to the name it is exposed as.
+type BuiltinDefinition struct {
+	Name    string
+	Builtin *Builtin
+}
+
+// Builtins is the table of functions provided by the host. It is shared by
+// the evaluator, which looks builtins up by name, and the compiler and vm,
+// which refer to them by their index in this slice. Builtins returning nil
+// produce the language's null value.
+var Builtins = []BuiltinDefinition{
+	{
+		"len",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *String:
+				return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *Array:
+				return &Integer{Value: int64(len(arg.Elements))}
+			default:
+				return newError("argument to `len` not supported, got %s", args[0].Type())
+			}
+		}},
+	},
+	{
+		"puts",
+		&Builtin{Fn: func(args ...Object) Object {
+			for _, arg := range args {
+				fmt.Println(arg.Inspect())
+			}
+
+			return nil
+		}},
+	},
+	{
+		"first",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			if len(arr.Elements) > 0 {
+				return arr.Elements[0]
+			}
+
+			return nil
+		}},
+	},
+	{
+		"rest",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+			if length > 0 {
+				newElements := make([]Object, length-1)
+				copy(newElements, arr.Elements[1:length])
+				return &Array{Elements: newElements}
+			}
+
+			return nil
+		}},
+	},
+	{
+		"push",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+
+			newElements := make([]Object, length+1)
+			copy(newElements, arr.Elements)
+			newElements[length] = args[1]
+
+			return &Array{Elements: newElements}
+		}},
+	},
+}
+
+// GetBuiltinByName returns the builtin registered under name, or nil.
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+	return nil
+}
+
+// RegisterBuiltin adds fn to the builtins table under name, replacing the
+// builtin previously registered with that name, if any. Existing entries
+// keep their index so that compiled bytecode stays valid.
+func RegisterBuiltin(name string, fn BuiltinFunction) {
+	builtin := &Builtin{Fn: fn}
+
+	for i, def := range Builtins {
+		if def.Name == name {
+			Builtins[i].Builtin = builtin
+			return
+		}
+	}
+
+	Builtins = append(Builtins, BuiltinDefinition{Name: name, Builtin: builtin})
+}
+
+// NewError returns an error object with a formatted message.
+func NewError(format string, a ...interface{}) *Error {
+	return newError(format, a...)
+}
+
+func newError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
@@ -1,20 +1,109 @@
 package object
 
+import "github.com/jolisper/monkey/ast"
+
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s}
+	c := make(map[string]ast.Node)
+	return &Environment{store: s, constants: c, outer: nil}
+}
+
+// NewEnclosedEnvironment creates a new environment whose lookups fall back
+// to outer when a name is not bound locally. Used for function call frames.
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// NewModuleEnvironment creates the top-level environment of a module
+// imported by a program, which shares the modules of the program.
+func NewModuleEnvironment(modules *Modules) *Environment {
+	env := NewEnvironment()
+	env.modules = modules
+	return env
 }
 
+// Environment binds names to values. A binding is either mutable, made by
+// Set, or constant, made by SetConst.
 type Environment struct {
-	store map[string]Object
+	store     map[string]Object
+	constants map[string]ast.Node // the declaration of each constant
+	outer     *Environment
+	modules   *Modules // only set in a top-level environment, see Modules
+}
+
+// Modules returns the modules imported by the program e belongs to, kept
+// by its top-level environment.
+func (e *Environment) Modules() *Modules {
+	root := e
+	for root.outer != nil {
+		root = root.outer
+	}
+	if root.modules == nil {
+		root.modules = NewModules()
+	}
+	return root.modules
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
 	return obj, ok
 }
 
+// Set binds name to value in this environment as a mutable binding,
+// replacing any binding of name it already holds.
 func (e *Environment) Set(name string, value Object) Object {
 	e.store[name] = value
+	delete(e.constants, name)
 	return value
 }
+
+// SetConst binds name to value in this environment as a constant declared
+// by decl, which Assign refuses to change.
+func (e *Environment) SetConst(name string, value Object, decl ast.Node) Object {
+	e.store[name] = value
+	e.constants[name] = decl
+	return value
+}
+
+// IsConst reports whether the nearest binding of name, in this environment
+// or an outer one, is a constant.
+func (e *Environment) IsConst(name string) bool {
+	if _, ok := e.store[name]; ok {
+		return e.constants[name] != nil
+	}
+	if e.outer != nil {
+		return e.outer.IsConst(name)
+	}
+	return false
+}
+
+// Redeclares reports whether declaring name at decl would replace a constant
+// that this environment itself, not an outer one, binds by another
+// declaration. Running the declaration of a constant again, as the body of
+// a loop does, rebinds it.
+func (e *Environment) Redeclares(name string, decl ast.Node) bool {
+	existing, ok := e.constants[name]
+	return ok && existing != decl
+}
+
+// Assign rebinds name in the nearest environment, this one or an outer one,
+// where it is bound. It reports false, changing nothing, when name is not
+// bound anywhere or its nearest binding is a constant.
+func (e *Environment) Assign(name string, value Object) bool {
+	if _, ok := e.store[name]; ok {
+		if e.constants[name] != nil {
+			return false
+		}
+		e.store[name] = value
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, value)
+	}
+	return false
+}
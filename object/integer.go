@@ -0,0 +1,122 @@
+package object
+
+import (
+	"math"
+	"math/big"
+)
+
+var (
+	minInt64 = big.NewInt(math.MinInt64)
+	maxInt64 = big.NewInt(math.MaxInt64)
+)
+
+// NewInteger returns v as an Integer when it fits in 64 bits and as a BigInt
+// otherwise, so that a BigInt always holds a value out of the int64 range.
+func NewInteger(v *big.Int) Object {
+	if v.Cmp(minInt64) >= 0 && v.Cmp(maxInt64) <= 0 {
+		return &Integer{Value: v.Int64()}
+	}
+	return &BigInt{Value: v}
+}
+
+// ToBigInt returns the value of an Integer or BigInt as a big.Int, or nil
+// for any other object.
+func ToBigInt(obj Object) *big.Int {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value)
+	case *BigInt:
+		return obj.Value
+	default:
+		return nil
+	}
+}
+
+// The following functions implement integer arithmetic promoting the result
+// to a BigInt when it overflows int64.
+
+func AddIntegers(a, b int64) Object {
+	sum := a + b
+	if (sum > a) == (b > 0) {
+		return &Integer{Value: sum}
+	}
+	return NewInteger(new(big.Int).Add(big.NewInt(a), big.NewInt(b)))
+}
+
+func SubIntegers(a, b int64) Object {
+	diff := a - b
+	if (diff < a) == (b > 0) {
+		return &Integer{Value: diff}
+	}
+	return NewInteger(new(big.Int).Sub(big.NewInt(a), big.NewInt(b)))
+}
+
+func MulIntegers(a, b int64) Object {
+	if a == 0 || b == 0 {
+		return &Integer{Value: 0}
+	}
+
+	product := a * b
+	if product/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64) {
+		return &Integer{Value: product}
+	}
+	return NewInteger(new(big.Int).Mul(big.NewInt(a), big.NewInt(b)))
+}
+
+// DivIntegers truncates towards zero. b must not be zero.
+func DivIntegers(a, b int64) Object {
+	if a == math.MinInt64 && b == -1 {
+		return NewInteger(new(big.Int).Neg(big.NewInt(a)))
+	}
+	return &Integer{Value: a / b}
+}
+
+func NegateInteger(a int64) Object {
+	if a == math.MinInt64 {
+		return NewInteger(new(big.Int).Neg(big.NewInt(a)))
+	}
+	return &Integer{Value: -a}
+}
+
+// MaxIntegerBits bounds the size of the results of `**` and `<<`, which
+// would otherwise let a program allocate an arbitrarily large integer in a
+// single operation.
+const MaxIntegerBits = 1 << 24
+
+// PowInteger raises a to the power b, which must not be negative. It reports
+// false when the result would be larger than MaxIntegerBits.
+func PowInteger(a, b *big.Int) (Object, bool) {
+	if a.CmpAbs(big.NewInt(1)) > 0 {
+		if !b.IsInt64() || b.Int64() > MaxIntegerBits/int64(a.BitLen()-1) {
+			return nil, false
+		}
+	}
+	return NewInteger(new(big.Int).Exp(a, b, nil)), true
+}
+
+// ShiftInteger shifts a by n bits, to the left or else to the right. The
+// right shift is arithmetic: it rounds towards negative infinity, so that
+// shifting a negative value far enough gives -1. n must not be negative. It
+// reports false when the result would be larger than MaxIntegerBits.
+func ShiftInteger(a, n *big.Int, left bool) (Object, bool) {
+	if !n.IsInt64() || n.Int64() > MaxIntegerBits {
+		if left && a.Sign() != 0 {
+			return nil, false
+		}
+		n = big.NewInt(MaxIntegerBits)
+	}
+	count := uint(n.Int64())
+
+	if !left {
+		return NewInteger(new(big.Int).Rsh(a, count)), true
+	}
+	if a.BitLen()+int(count) > MaxIntegerBits {
+		return nil, false
+	}
+	return NewInteger(new(big.Int).Lsh(a, count)), true
+}
+
+// NotInteger returns the bitwise complement of a, that is -a - 1.
+func NotInteger(a *big.Int) Object {
+	return NewInteger(new(big.Int).Not(a))
+}
@@ -0,0 +1,53 @@
+package object
+
+// Iterator steps through the elements of an array, the characters of a
+// string or the keys of a hash, in order. It backs for loops in both the
+// evaluator and the vm.
+type Iterator struct {
+	items []Object
+	next  int
+}
+
+// NewIterator returns an iterator over obj, or false when obj cannot be
+// iterated over. The items are captured up front, so changes to obj while
+// iterating are not seen.
+func NewIterator(obj Object) (*Iterator, bool) {
+	var items []Object
+
+	switch obj := obj.(type) {
+	case *Array:
+		items = make([]Object, len(obj.Elements))
+		copy(items, obj.Elements)
+	case *String:
+		for _, r := range obj.Value {
+			items = append(items, &String{Value: string(r)})
+		}
+	case *Hash:
+		for _, pair := range obj.SortedPairs() {
+			items = append(items, pair.Key)
+		}
+	default:
+		return nil, false
+	}
+
+	return &Iterator{items: items}, true
+}
+
+// Next returns the next item, or false once all of them were returned.
+func (it *Iterator) Next() (Object, bool) {
+	if it.next >= len(it.items) {
+		return nil, false
+	}
+
+	item := it.items[it.next]
+	it.next++
+	return item, true
+}
+
+func (it *Iterator) Type() ObjectType {
+	return ITERATOR_OBJ
+}
+
+func (it *Iterator) Inspect() string {
+	return "iterator"
+}
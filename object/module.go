@@ -0,0 +1,74 @@
+package object
+
+import "strings"
+
+// Module is the namespace of an imported file: the environment its
+// top-level statements were evaluated in. Its exported bindings are read
+// with module.name.
+type Module struct {
+	Name string       // the name the import statement binds
+	Path string       // the file the module was read from
+	Env  *Environment // the top-level bindings of the module
+}
+
+func (m *Module) Type() ObjectType {
+	return MODULE_OBJ
+}
+
+func (m *Module) Inspect() string {
+	return "module " + m.Name
+}
+
+// Export returns the value of the exported binding name of the module. The
+// top-level bindings are exported, except the ones whose name starts with
+// an underscore.
+func (m *Module) Export(name string) (Object, bool) {
+	if strings.HasPrefix(name, "_") {
+		return nil, false
+	}
+	return m.Env.Get(name)
+}
+
+// Modules records the modules imported by a program, by file path, so that
+// a file is evaluated once however many times it is imported. It also
+// tracks the imports being evaluated to detect import cycles.
+type Modules struct {
+	loaded  map[string]*Module
+	loading []string // paths of the imports being evaluated, innermost last
+}
+
+func NewModules() *Modules {
+	return &Modules{loaded: make(map[string]*Module)}
+}
+
+// Get returns the module already imported from path.
+func (m *Modules) Get(path string) (*Module, bool) {
+	module, ok := m.loaded[path]
+	return module, ok
+}
+
+// Set records module as the one imported from its path.
+func (m *Modules) Set(module *Module) {
+	m.loaded[module.Path] = module
+}
+
+// StartLoading records that the module in path is being evaluated, until
+// DoneLoading is called for it. When path is already being evaluated it
+// records nothing and returns the cycle of imports leading back to it,
+// starting and ending with path.
+func (m *Modules) StartLoading(path string) []string {
+	for i, loading := range m.loading {
+		if loading == path {
+			cycle := append([]string{}, m.loading[i:]...)
+			return append(cycle, path)
+		}
+	}
+
+	m.loading = append(m.loading, path)
+	return nil
+}
+
+// DoneLoading records that the module most recently started is evaluated.
+func (m *Modules) DoneLoading() {
+	m.loading = m.loading[:len(m.loading)-1]
+}
@@ -0,0 +1,48 @@
+package parser
+
+import "github.com/jolisper/monkey/token"
+
+type ErrorKind string
+
+const (
+	// The next token is not the one the grammar requires
+	UnexpectedToken ErrorKind = "unexpected token"
+	// The token cannot start an expression
+	NoPrefixParseFn ErrorKind = "no prefix parse function"
+	// The literal cannot be converted to a value, e.g. an integer too big
+	InvalidLiteral ErrorKind = "invalid literal"
+	// The lexer could not make sense of the input
+	IllegalToken ErrorKind = "illegal token"
+	// The input ends inside a string literal
+	UnterminatedString ErrorKind = "unterminated string"
+	// The left side of an assignment is not a variable or an index expression
+	InvalidAssignment ErrorKind = "invalid assignment"
+	// A break or continue statement is not inside a loop
+	OutsideLoop ErrorKind = "outside loop"
+	// The source is not valid UTF-8
+	InvalidEncoding ErrorKind = "invalid encoding"
+	// The input ends inside a block comment
+	UnterminatedComment ErrorKind = "unterminated comment"
+	// The imported path does not end with a name usable as an identifier
+	InvalidImport ErrorKind = "invalid import"
+)
+
+// ParseError describes a syntax error found by the parser.
+type ParseError struct {
+	Kind     ErrorKind
+	Expected token.TokenType // only set for UnexpectedToken
+	Got      token.Token     // the offending token
+	Pos      token.Position
+	Msg      string
+}
+
+// Error returns the message prefixed by the position of the error.
+func (e *ParseError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Incomplete reports whether the error was caused by the input ending too
+// early, which means that more input could still make it valid.
+func (e *ParseError) Incomplete() bool {
+	return e.Got.Type == token.EOF || e.Kind == UnterminatedString || e.Kind == UnterminatedComment
+}
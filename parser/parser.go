@@ -2,35 +2,115 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/jolisper/monkey/ast"
 	"github.com/jolisper/monkey/lexer"
 	"github.com/jolisper/monkey/token"
 )
 
+// Operator precedence, from the loosest to the tightest binding:
+//
+//	ASSIGN       = += -= *= /= %= **= &= |= ^= <<= >>=   right-associative
+//	LOGICALOR    ||
+//	LOGICAL      &&
+//	EQUALS       == !=
+//	LESSGREATER  < > <= >=
+//	BITOR        |
+//	BITXOR       ^
+//	BITAND       &
+//	SHIFT        << >>
+//	SUM          + -
+//	PRODUCT      * / %
+//	POWER        **            right-associative
+//	PREFIX       -X !X ~X
+//	CALL         myFunction(X)
+//	INDEX        array[index]
+//
+// All binary operators but assignments and ** are left-associative. Prefix operators bind
+// tighter than **, so -2 ** 2 is (-2) ** 2.
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // x = y or x += y
+	LOGICALOR   // ||
+	LOGICAL     // &&
 	EQUALS      // ==
 	LESSGREATER // > or <
+	BITOR       // |
+	BITXOR      // ^
+	BITAND      // &
+	SHIFT       // << or >>
 	SUM         // +
-	PRODUCT     // *
+	PRODUCT     // * / %
+	POWER       // **
 	PREFIX      // -X or !X
 	CALL        // myFunction(X)
+	INDEX       // array[index]
 )
 
 // Precedence
 var precedences = map[token.TokenType]int{
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.PERCENT_ASSIGN:  ASSIGN,
+	token.POWER_ASSIGN:    ASSIGN,
+	token.BIT_AND_ASSIGN:  ASSIGN,
+	token.BIT_OR_ASSIGN:   ASSIGN,
+	token.BIT_XOR_ASSIGN:  ASSIGN,
+	token.SHL_ASSIGN:      ASSIGN,
+	token.SHR_ASSIGN:      ASSIGN,
+
+	token.OR:       LOGICALOR,
+	token.AND:      LOGICAL,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LT_EQ:    LESSGREATER,
+	token.GT_EQ:    LESSGREATER,
+	token.BIT_OR:   BITOR,
+	token.BIT_XOR:  BITXOR,
+	token.BIT_AND:  BITAND,
+	token.SHL:      SHIFT,
+	token.SHR:      SHIFT,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
+	token.PERCENT:  PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.POWER:    POWER,
 	token.LPAREN:   CALL,
+	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
+}
+
+// Operators grouping from the right, a ** b ** c is a ** (b ** c).
+var rightAssociative = map[token.TokenType]bool{
+	token.POWER: true,
+}
+
+// Precedence returns how tightly the infix operator t binds its operands,
+// LOWEST when t is not an infix operator. Tools printing an AST back as
+// source use it to know where parentheses are needed.
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+// RightAssociative reports whether the infix operator t groups from the
+// right, like ** and the assignments.
+func RightAssociative(t token.TokenType) bool {
+	return rightAssociative[t] || precedences[t] == ASSIGN
 }
 
 type (
@@ -48,11 +128,24 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParserFn) {
 
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []*ParseError
+
+	// Set when an error is reported and cleared once the parser has skipped
+	// to the next statement boundary. Errors reported meanwhile are
+	// consequences of the first one and are dropped.
+	panicMode bool
 
 	curToken  token.Token
 	peekToken token.Token
 
+	// Number of loops enclosing the current token within the current
+	// function, break and continue are only valid when it is not zero.
+	loopDepth int
+
+	// Comments returned by the lexer, in source order. The lexer only
+	// returns them when asked to with lexer.EmitComments.
+	comments []token.Token
+
 	prefixParserFns map[token.TokenType]prefixParserFn
 	infixParserFns  map[token.TokenType]infixParserFn
 }
@@ -60,33 +153,58 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*ParseError{},
 	}
 
 	p.nextToken()
 	p.nextToken()
 
 	p.prefixParserFns = make(map[token.TokenType]prefixParserFn)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.infixParserFns = make(map[token.TokenType]infixParserFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHL, p.parseInfixExpression)
+	p.registerInfix(token.SHR, p.parseInfixExpression)
+	for tokenType, precedence := range precedences {
+		if precedence == ASSIGN {
+			p.registerInfix(tokenType, p.parseAssignExpression)
+		}
+	}
+	p.registerInfix(token.AND, p.parseLogicalExpression)
+	p.registerInfix(token.OR, p.parseLogicalExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 
 	return p
 }
@@ -94,6 +212,18 @@ func New(l *lexer.Lexer) *Parser {
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	for p.peekToken.Type == token.COMMENT {
+		p.comments = append(p.comments, p.peekToken)
+		p.peekToken = p.l.NextToken()
+	}
+}
+
+// Comments returns the comments met while parsing, in source order, each
+// with its position. Comments are part of the token stream only when the
+// lexer was asked to keep them with lexer.EmitComments.
+func (p *Parser) Comments() []token.Token {
+	return p.comments
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -101,8 +231,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		if stmt != nil {
+		if stmt := p.parseStatementOrSynchronize(); stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
 		p.nextToken()
@@ -111,19 +240,92 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []*ParseError {
 	return p.errors
 }
 
+// Parses a statement. When the statement has errors it is discarded and the
+// parser skips ahead to the next statement boundary, so that parsing can go
+// on and report the errors of the following statements too.
+func (p *Parser) parseStatementOrSynchronize() ast.Statement {
+	stmt := p.parseStatement()
+
+	if p.panicMode {
+		p.synchronize()
+		return nil
+	}
+
+	return stmt
+}
+
+// Advances until the current token ends a statement (a semicolon) or the
+// next token starts a new one (let, return) or closes the enclosing block.
+// Blocks opened while skipping are skipped as a whole.
+func (p *Parser) synchronize() {
+	p.panicMode = false
+	depth := 0
+
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			}
+		}
+
+		if depth == 0 {
+			if p.curTokenIs(token.SEMICOLON) {
+				return
+			}
+
+			switch p.peekToken.Type {
+			case token.LET, token.CONST, token.RETURN, token.WHILE, token.FOR, token.IMPORT, token.RBRACE, token.EOF:
+				return
+			}
+		}
+
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
-		return p.parseLetStatement()
+	case token.LET, token.CONST:
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
 	case token.RETURN:
-		return p.parseReturnStatement()
+		if stmt := p.parseReturnStatement(); stmt != nil {
+			return stmt
+		}
+	case token.WHILE:
+		if stmt := p.parseWhileStatement(); stmt != nil {
+			return stmt
+		}
+	case token.FOR:
+		if stmt := p.parseForStatement(); stmt != nil {
+			return stmt
+		}
+	case token.BREAK:
+		if stmt := p.parseBreakStatement(); stmt != nil {
+			return stmt
+		}
+	case token.CONTINUE:
+		if stmt := p.parseContinueStatement(); stmt != nil {
+			return stmt
+		}
+	case token.IMPORT:
+		if stmt := p.parseImportStatement(); stmt != nil {
+			return stmt
+		}
 	default:
-		return p.parseExpressionStatement()
+		if stmt := p.parseExpressionStatement(); stmt != nil {
+			return stmt
+		}
 	}
+	return nil
 }
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
@@ -146,7 +348,7 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -160,13 +362,158 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	if name := stmt.Name(); !isIdentifier(name) {
+		p.addError(&ParseError{
+			Kind: InvalidImport,
+			Got:  p.curToken,
+			Pos:  p.curToken.Pos,
+			Msg:  fmt.Sprintf("cannot import %q: %q is not a valid module name", stmt.Path.Value, name),
+		})
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// Reports whether name could be written as an identifier: a letter or an
+// underscore followed by letters, underscores and digits, and no keyword.
+func isIdentifier(name string) bool {
+	for i, ch := range name {
+		if !unicode.IsLetter(ch) && ch != '_' && (i == 0 || !unicode.IsDigit(ch)) {
+			return false
+		}
+	}
+	return name != "" && token.LookupIdent(name) == token.IDENT
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+	p.checkInLoop()
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
 	return stmt
 }
 
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+	p.checkInLoop()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// Reports an error when the current break or continue token is not inside
+// a loop of the current function.
+func (p *Parser) checkInLoop() {
+	if p.loopDepth > 0 {
+		return
+	}
+
+	p.addError(&ParseError{
+		Kind: OutsideLoop,
+		Got:  p.curToken,
+		Pos:  p.curToken.Pos,
+		Msg:  fmt.Sprintf("%s outside loop", p.curToken.Literal),
+	})
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	defer untrace(trace("parseExpressionStatement"))
 
@@ -182,8 +529,12 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(&ParseError{
+		Kind: NoPrefixParseFn,
+		Got:  p.curToken,
+		Pos:  p.curToken.Pos,
+		Msg:  fmt.Sprintf("no prefix parse function for %s found", t),
+	})
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
@@ -239,6 +590,61 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Left:     left,
 	}
 
+	precedence := p.curPrecedence()
+	if rightAssociative[p.curToken.Type] {
+		// Binding the right operand one level looser lets it absorb a
+		// following operator of the same precedence.
+		precedence--
+	}
+	p.nextToken()
+
+	expression.Right = p.parseExpression(precedence)
+
+	return expression
+}
+
+// Parses an assignment to left, which must be a variable or an index
+// expression. Assignments group to the right, so a = b = 1 assigns 1 to
+// both a and b.
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	defer untrace(trace("parseAssignExpression: " + p.curToken.Literal))
+
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   left,
+		Operator: p.curToken.Literal,
+	}
+
+	switch ast.Unparen(left).(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	case nil:
+		// The error that left the target empty was already reported
+		return nil
+	default:
+		p.addError(&ParseError{
+			Kind: InvalidAssignment,
+			Got:  p.curToken,
+			Pos:  left.Pos(),
+			Msg:  fmt.Sprintf("cannot assign to %s", left.String()),
+		})
+		return nil
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
+func (p *Parser) parseLogicalExpression(left ast.Expression) ast.Expression {
+	defer untrace(trace("parseLogicalExpression: " + p.curToken.Literal))
+
+	expression := &ast.LogicalExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
 	precedence := p.curPrecedence()
 	p.nextToken()
 
@@ -247,6 +653,84 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseIllegal() ast.Expression {
+	literal := p.curToken.Literal
+
+	if strings.HasPrefix(literal, "/*") {
+		p.addError(&ParseError{
+			Kind: UnterminatedComment,
+			Got:  p.curToken,
+			Pos:  p.curToken.Pos,
+			Msg:  "comment not terminated",
+		})
+		return nil
+	}
+
+	if strings.HasPrefix(literal, `"`) && !isTerminatedString(literal) {
+		p.addError(&ParseError{
+			Kind: UnterminatedString,
+			Got:  p.curToken,
+			Pos:  p.curToken.Pos,
+			Msg:  "string literal not terminated",
+		})
+		return nil
+	}
+
+	if !utf8.ValidString(literal) {
+		p.addError(&ParseError{
+			Kind: InvalidEncoding,
+			Got:  p.curToken,
+			Pos:  invalidUTF8Position(p.curToken),
+			Msg:  "invalid UTF-8 encoding",
+		})
+		return nil
+	}
+
+	p.addError(&ParseError{
+		Kind: IllegalToken,
+		Got:  p.curToken,
+		Pos:  p.curToken.Pos,
+		Msg:  fmt.Sprintf("illegal token %q", literal),
+	})
+	return nil
+}
+
+// Returns the position of the first byte of tok that is not valid UTF-8,
+// for tokens like string literals that span several chars.
+func invalidUTF8Position(tok token.Token) token.Position {
+	pos := tok.Pos
+
+	for i, r := range tok.Literal {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(tok.Literal[i:]); size == 1 {
+				pos.Offset += i
+				return pos
+			}
+		}
+		if r == '\n' {
+			pos.Line++
+			pos.Column = 0
+		}
+		pos.Column++
+	}
+
+	return pos
+}
+
+// Reports whether the raw source of a string literal has a closing quote,
+// taking escape sequences into account.
+func isTerminatedString(raw string) bool {
+	for i := 1; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '"':
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
@@ -257,9 +741,40 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if err == nil {
+		lit.Value = value
+		return lit
+	}
+
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		if value, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			lit.Big = value
+			return lit
+		}
+	}
+
+	p.addError(&ParseError{
+		Kind: InvalidLiteral,
+		Got:  p.curToken,
+		Pos:  p.curToken.Pos,
+		Msg:  fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+	})
+	return nil
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	defer untrace(trace("parseFloatLiteral: " + p.curToken.Literal))
+
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(&ParseError{
+			Kind: InvalidLiteral,
+			Got:  p.curToken,
+			Pos:  p.curToken.Pos,
+			Msg:  fmt.Sprintf("could not parse %q as float", p.curToken.Literal),
+		})
 		return nil
 	}
 
@@ -268,21 +783,27 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	defer untrace(trace("parseGroupedExpression: " + p.curToken.Literal))
+	expression := &ast.ParenExpression{Token: p.curToken}
 	p.nextToken()
 
-	exp := p.parseExpression(LOWEST)
+	expression.Expression = p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(token.RPAREN) || expression.Expression == nil {
 		return nil
 	}
+	expression.Rparen = p.curToken.Pos
 
-	return exp
+	return expression
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
@@ -326,14 +847,24 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-
-		if stmt != nil {
+		if stmt := p.parseStatementOrSynchronize(); stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
 
+	block.Rbrace = p.curToken.Pos
+
+	if p.curTokenIs(token.EOF) {
+		p.addError(&ParseError{
+			Kind:     UnexpectedToken,
+			Expected: token.RBRACE,
+			Got:      p.curToken,
+			Pos:      p.curToken.Pos,
+			Msg:      fmt.Sprintf("expected %s to close the block, got %s instead", token.RBRACE, token.EOF),
+		})
+	}
+
 	return block
 }
 
@@ -345,12 +876,39 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	}
 
 	lit.Parameters = p.parseFunctionParameters()
+	lit.Rparen = p.curToken.Pos
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// Loops around the function literal do not extend into its body
+	outerLoopDepth := p.loopDepth
+	p.loopDepth = 0
+	lit.Body = p.parseBlockStatement()
+	p.loopDepth = outerLoopDepth
+
+	return lit
+}
+
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lit.Parameters = p.parseFunctionParameters()
+	lit.Rparen = p.curToken.Pos
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
+	outerLoopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = outerLoopDepth
 
 	return lit
 }
@@ -384,32 +942,100 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseCallArguments()
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Rparen = p.curToken.Pos
 	return exp
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Rbrack = p.curToken.Pos
+	return array
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Left: left}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Member = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	exp.Rbrack = p.curToken.Pos
+
+	return exp
+}
+
+// Block statements are only parsed where the grammar expects them, after
+// if, else and fn, so a '{' found in expression position always opens a
+// hash literal.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+	hash.Pairs = []ast.HashPair{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
 
-	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return args
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	hash.Rbrace = p.curToken.Pos
+
+	return hash
+}
+
+// Parses a comma separated list of expressions up to the end token, used
+// by call arguments and array literals.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	list = append(list, p.parseExpression(LOWEST))
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
 		return nil
 	}
 
-	return args
+	return list
 }
 
 // Helpers:
@@ -451,8 +1077,23 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t,
-		p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(&ParseError{
+		Kind:     UnexpectedToken,
+		Expected: t,
+		Got:      p.peekToken,
+		Pos:      p.peekToken.Pos,
+		Msg: fmt.Sprintf("expected next token to be %s, got %s instead",
+			t,
+			p.peekToken.Type),
+	})
+}
+
+// Records err unless the parser is already recovering from a previous one.
+func (p *Parser) addError(err *ParseError) {
+	if p.panicMode {
+		return
+	}
+
+	p.panicMode = true
+	p.errors = append(p.errors, err)
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/jolisper/monkey/ast"
 	"github.com/jolisper/monkey/lexer"
 	"github.com/jolisper/monkey/parser"
+	"github.com/jolisper/monkey/token"
 )
 
 func TestLetStatements(t *testing.T) {
@@ -43,6 +44,90 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatement(t *testing.T) {
+	input := "const answer = 42"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+	if !stmt.IsConst() {
+		t.Errorf("stmt.IsConst() is false for %q", input)
+	}
+	if stmt.Name.Value != "answer" {
+		t.Errorf("stmt.Name.Value not 'answer'. got=%s", stmt.Name.Value)
+	}
+	if !testLiteralExpression(t, stmt.Value, 42) {
+		return
+	}
+	if stmt.String() != "const answer = 42;" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestImportStatement(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedPath string
+		expectedName string
+	}{
+		{`import "math"`, "math", "math"},
+		{`import "lib/strings.mk";`, "lib/strings.mk", "strings"},
+		{`import "../shared/_util"`, "../shared/_util", "_util"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ImportStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Path.Value != tt.expectedPath {
+			t.Errorf("stmt.Path.Value not %q. got=%q", tt.expectedPath, stmt.Path.Value)
+		}
+		if stmt.Name() != tt.expectedName {
+			t.Errorf("stmt.Name() not %q. got=%q", tt.expectedName, stmt.Name())
+		}
+	}
+}
+
+func TestImportStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import math`, "1:8: expected next token to be STRING, got IDENT instead"},
+		{`import "my-lib"`, `1:8: cannot import "my-lib": "my-lib" is not a valid module name`},
+		{`import "lib/2d"`, `1:8: cannot import "lib/2d": "2d" is not a valid module name`},
+		{`import "lib/fn.mk"`, `1:8: cannot import "lib/fn.mk": "fn" is not a valid module name`},
+		{`import ""`, `1:8: cannot import "": "" is not a valid module name`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser error for %q, got none", tt.input)
+			continue
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, errors[0].Error())
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -141,6 +226,200 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	input := "123456789012345678901234567890;"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Big == nil || literal.Big.String() != "123456789012345678901234567890" {
+		t.Errorf("literal.Big wrong. got=%v", literal.Big)
+	}
+	if literal.String() != "123456789012345678901234567890" {
+		t.Errorf("literal.String() wrong. got=%q", literal.String())
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{"1e3;", 1000},
+		{"2.5e-1;", 0.25},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello world";`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != "hello world" {
+		t.Errorf("literal.Value not %q. got=%q", "hello world", literal.Value)
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingEmptyArrayLiterals(t *testing.T) {
+	input := "[]"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 0 {
+		t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`{}`, []string{}},
+		{`{"one": 1, "two": 2, "three": 3}`, []string{"one: 1", "two: 2", "three: 3"}},
+		{`{true: 1, 2: "two",}`, []string{"true: 1", "2: two"}},
+		{`{"one": 0 + 1, "two": 10 - 8}`, []string{"one: (0 + 1)", "two: (10 - 8)"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+		}
+
+		if len(hash.Pairs) != len(tt.expected) {
+			t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+		}
+
+		for i, pair := range hash.Pairs {
+			got := pair.Key.String() + ": " + pair.Value.String()
+			if got != tt.expected[i] {
+				t.Errorf("wrong pair %d. expected=%q, got=%q", i, tt.expected[i], got)
+			}
+		}
+	}
+}
+
+func TestParsingHashLiteralErrors(t *testing.T) {
+	tests := []string{
+		`{"one" 1}`,
+		`{"one": 1 "two": 2}`,
+		`{"one": 1`,
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := parser.New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -263,6 +542,70 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a * b * c",
 			"((a * b) * c)",
 		},
+		{
+			"a % b * c",
+			"((a % b) * c)",
+		},
+		{
+			"a + b % c",
+			"(a + (b % c))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a == b && !c",
+			"((a == b) && (!c))",
+		},
+		{
+			"a < b || f(c)",
+			"((a < b) || f(c))",
+		},
+		{
+			"a <= b == b >= c",
+			"((a <= b) == (b >= c))",
+		},
+		{
+			"a ** b ** c",
+			"(a ** (b ** c))",
+		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"-a ** b",
+			"((-a) ** b)",
+		},
+		{
+			"~a & b",
+			"((~a) & b)",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a << b + c",
+			"(a << (b + c))",
+		},
+		{
+			"a & b << c >> d",
+			"(a & ((b << c) >> d))",
+		},
+		{
+			"a | b == c",
+			"((a | b) == c)",
+		},
+		{
+			"a < b | c",
+			"(a < (b | c))",
+		},
 		{
 			"a * b / c",
 			"((a * b) / c)",
@@ -347,6 +690,30 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"fns[0](1)",
+			"(fns[0])(1)",
+		},
+		{
+			"-math.abs(x).y[0] * 2",
+			"((-(((math.abs)(x).y)[0])) * 2)",
+		},
+		{
+			"x += a || b * 2",
+			"x += (a || (b * 2))",
+		},
+		{
+			"arr[i + 1] = f(x)",
+			"(arr[(i + 1)]) = f(x)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -507,6 +874,168 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { x; break; }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Body does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T",
+			program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d\n", len(stmt.Body.Statements))
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.BreakStatement); !ok {
+		t.Fatalf("Statements[1] is not ast.BreakStatement. got=%T",
+			stmt.Body.Statements[1])
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for (item in [1, 2]) { if (item > 1) { continue; } item }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Body does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T",
+			program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Variable, "item") {
+		return
+	}
+
+	if stmt.Iterable.String() != "[1, 2]" {
+		t.Errorf("stmt.Iterable wrong. got=%q", stmt.Iterable.String())
+	}
+
+	if stmt.String() != "for (item in [1, 2]) if(item > 1) continue;item" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestLoopControlErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"break;", []string{"1:1: break outside loop"}},
+		{"if (true) { continue }", []string{"1:13: continue outside loop"}},
+		{"while (true) { fn() { break; } }", []string{"1:23: break outside loop"}},
+		{"while (true) { fn() { while (true) { break; } }; break; }", []string{}},
+		{"for (1 in x) { }", []string{"1:6: expected next token to be IDENT, got INT instead"}},
+		{"for (x of y) { }", []string{"1:8: expected next token to be IN, got IDENT instead"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. want=%d, got=%d (%v)",
+				tt.input, len(tt.expected), len(errors), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != tt.expected[i] {
+				t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected[i], err.Error())
+			}
+		}
+	}
+}
+
+func TestAssignExpression(t *testing.T) {
+	input := "a = b **= c"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	outer, ok := stmt.Expression.(*ast.AssignExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.AssignExpression. got=%T", stmt.Expression)
+	}
+	if outer.Operator != "=" {
+		t.Errorf("outer.Operator is not '='. got=%q", outer.Operator)
+	}
+	if !testIdentifier(t, outer.Target, "a") {
+		return
+	}
+
+	inner, ok := outer.Value.(*ast.AssignExpression)
+	if !ok {
+		t.Fatalf("outer.Value is not ast.AssignExpression. got=%T", outer.Value)
+	}
+	if inner.Operator != "**=" {
+		t.Errorf("inner.Operator is not '**='. got=%q", inner.Operator)
+	}
+	if !testIdentifier(t, inner.Target, "b") {
+		return
+	}
+	testIdentifier(t, inner.Value, "c")
+}
+
+func TestAssignExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 = 2", "1:1: cannot assign to 1"},
+		{"f() += 1", "1:1: cannot assign to f()"},
+		{"a + b = c", "1:1: cannot assign to (a + b)"},
+		{"m.x = 1", "1:1: cannot assign to (m.x)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser error for %q, got none", tt.input)
+			continue
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, errors[0].Error())
+		}
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -584,6 +1113,53 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T",
+			stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d\n",
+			len(macro.Parameters))
+	}
+
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d\n",
+			len(macro.Body.Statements))
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T",
+			macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -676,6 +1252,215 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let = 5;", "1:5: expected next token to be IDENT, got = instead"},
+		{"let x 5;", "1:7: expected next token to be =, got INT instead"},
+		{"let x = 1;\n  );", "2:3: no prefix parse function for ) found"},
+		{"let año 5;", "1:9: expected next token to be =, got INT instead"},
+		{"let x = \xff;", "1:9: invalid UTF-8 encoding"},
+		{"let s = \"añ\nb\xffc\";", "2:2: invalid UTF-8 encoding"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements []string
+	}{
+		{
+			"let = 5; let y = 10; let 3;",
+			[]string{
+				"1:5: expected next token to be IDENT, got = instead",
+				"1:26: expected next token to be IDENT, got INT instead",
+			},
+			[]string{"let y = 10;"},
+		},
+		{
+			"let x = (1 + ; x + 1;",
+			[]string{"1:14: no prefix parse function for ; found"},
+			[]string{"(x + 1)"},
+		},
+		{
+			"let f = fn(x) {\n  let = 1;\n  x\n};\nlet g = 2 +;\nreturn f(g);",
+			[]string{
+				"2:7: expected next token to be IDENT, got = instead",
+				"5:12: no prefix parse function for ; found",
+			},
+			[]string{"let f = fn(x) x;", "return f(g);"},
+		},
+		{
+			"if (x { 1 }\nlet y = 2;",
+			[]string{"1:7: expected next token to be ), got { instead"},
+			[]string{"let y = 2;"},
+		},
+		{
+			"let s = \"abc\nlet y = 2;",
+			[]string{"1:9: string literal not terminated"},
+			[]string{},
+		},
+		{
+			"let x = 5",
+			[]string{},
+			[]string{"let x = 5;"},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. want=%d, got=%d (%v)",
+				tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != tt.expectedErrors[i] {
+				t.Errorf("wrong error %d. want=%q, got=%q", i, tt.expectedErrors[i], err.Error())
+			}
+		}
+
+		if len(program.Statements) != len(tt.expectedStatements) {
+			t.Errorf("wrong number of statements for %q. want=%d, got=%d",
+				tt.input, len(tt.expectedStatements), len(program.Statements))
+			continue
+		}
+		for i, stmt := range program.Statements {
+			if stmt.String() != tt.expectedStatements[i] {
+				t.Errorf("wrong statement %d. want=%q, got=%q", i, tt.expectedStatements[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// adds two numbers
+let add = fn(a, b) {
+	a + /* inline */ b // trailing
+};`
+
+	l := lexer.New(input)
+	l.EmitComments(true)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if got := program.String(); got != "let add = fn(a, b) (a + b);" {
+		t.Errorf("program wrong. got=%q", got)
+	}
+
+	expected := []struct {
+		literal string
+		line    int
+	}{
+		{"// adds two numbers", 1},
+		{"/* inline */", 3},
+		{"// trailing", 3},
+	}
+
+	comments := p.Comments()
+	if len(comments) != len(expected) {
+		t.Fatalf("wrong number of comments. want=%d, got=%d", len(expected), len(comments))
+	}
+	for i, tt := range expected {
+		if comments[i].Literal != tt.literal {
+			t.Errorf("comments[%d] literal wrong. want=%q, got=%q", i, tt.literal, comments[i].Literal)
+		}
+		if comments[i].Pos.Line != tt.line {
+			t.Errorf("comments[%d] line wrong. want=%d, got=%d", i, tt.line, comments[i].Pos.Line)
+		}
+	}
+}
+
+func TestIncompleteInput(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{"let add = fn(a, b) {", true},
+		{"let add = fn(a, b) { a + b", true},
+		{"if (x > 1) { 1 } else {", true},
+		{"add(1,", true},
+		{"(1 + 2", true},
+		{"[1, 2", true},
+		{`{"a": 1`, true},
+		{"1 +", true},
+		{"let x =", true},
+		{`"unterminated`, true},
+		{`"escaped quote \"`, true},
+		{"let x = 1; /* not closed", true},
+		{"1 + /* not closed", true},
+		{"let x = 1;", false},
+		{"let = 1;", false},
+		{"let = 1; fn() {", false},
+		{"1 + }", false},
+		{`"bad \q"`, false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		incomplete := len(errors) > 0
+		for _, err := range errors {
+			incomplete = incomplete && err.Incomplete()
+		}
+
+		if incomplete != tt.incomplete {
+			t.Errorf("wrong completeness for %q. want=%t, got=%t (%v)",
+				tt.input, tt.incomplete, incomplete, errors)
+		}
+	}
+}
+
+func TestParseErrorDetails(t *testing.T) {
+	l := lexer.New("let x 5;")
+	p := parser.New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got=%d", len(errors))
+	}
+
+	err := errors[0]
+	if err.Kind != parser.UnexpectedToken {
+		t.Errorf("err.Kind wrong. want=%q, got=%q", parser.UnexpectedToken, err.Kind)
+	}
+	if err.Expected != token.ASSIGN {
+		t.Errorf("err.Expected wrong. want=%q, got=%q", token.ASSIGN, err.Expected)
+	}
+	if err.Got.Type != token.INT || err.Got.Literal != "5" {
+		t.Errorf("err.Got wrong. got=%+v", err.Got)
+	}
+	if err.Pos.Line != 1 || err.Pos.Column != 7 {
+		t.Errorf("err.Pos wrong. got=%s", err.Pos)
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
@@ -2,8 +2,8 @@ package repl
 
 import (
 	"bufio"
-	"fmt"
 	"io"
+	"strings"
 
 	"github.com/jolisper/monkey/evaluator"
 	"github.com/jolisper/monkey/lexer"
@@ -13,6 +13,9 @@ import (
 
 const PROMPT = ">> "
 
+// Shown while the statement being typed is not complete yet
+const CONTINUATION_PROMPT = ".. "
+
 const MONKEY_FACE = `            __,__
    .--.  .-"     "-.  .--.
   / .. \/  .-. .-.  \/ .. \
@@ -29,24 +32,52 @@ const MONKEY_FACE = `            __,__
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
+
+	var input strings.Builder
 
 	for {
-		fmt.Printf(PROMPT)
+		if input.Len() == 0 {
+			io.WriteString(out, PROMPT)
+		} else {
+			io.WriteString(out, CONTINUATION_PROMPT)
+		}
+
 		scanned := scanner.Scan()
 		if !scanned {
 			return
 		}
 
 		line := scanner.Text()
-		l := lexer.New(line)
+
+		// An empty line submits the pending input even when it is not
+		// complete, which is the way out of a mistyped continuation.
+		forceSubmit := input.Len() > 0 && strings.TrimSpace(line) == ""
+
+		input.WriteString(line)
+		input.WriteString("\n")
+
+		l := lexer.New(input.String())
 		p := parser.New(l)
 
 		program := p.ParseProgram()
+		if !forceSubmit && isIncomplete(p.Errors()) {
+			continue
+		}
+		input.Reset()
+
 		if len(p.Errors()) != 0 {
 			printParserErrors(out, p.Errors())
 			continue
 		}
 
+		evaluator.DefineMacros(program, macroEnv)
+		if _, errObj := evaluator.ExpandMacros(program, macroEnv); errObj != nil {
+			io.WriteString(out, errObj.Inspect())
+			io.WriteString(out, "\n")
+			continue
+		}
+
 		evaluated := evaluator.Eval(program, env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
@@ -55,11 +86,27 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
+// The input is incomplete when parsing it failed only because it ended too
+// early, e.g. inside a block, after an infix operator or inside a string.
+func isIncomplete(errors []*parser.ParseError) bool {
+	if len(errors) == 0 {
+		return false
+	}
+
+	for _, err := range errors {
+		if !err.Incomplete() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func printParserErrors(out io.Writer, errors []*parser.ParseError) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
 	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, err := range errors {
+		io.WriteString(out, "\t"+err.Error()+"\n")
 	}
 }
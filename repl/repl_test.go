@@ -0,0 +1,76 @@
+package repl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jolisper/monkey/repl"
+)
+
+func TestMultiLineInput(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+};
+add(1,
+  2)
+"multi
+line"
+1 +
+2
+`
+
+	expected := ">> .. .. >> " +
+		".. 3\n" +
+		">> .. multi\nline\n" +
+		">> .. 3\n" +
+		">> "
+
+	var out bytes.Buffer
+	repl.Start(strings.NewReader(input), &out)
+
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot =%q", expected, out.String())
+	}
+}
+
+func TestEmptyLineSubmitsIncompleteInput(t *testing.T) {
+	input := "let f = fn() {\n\n1\n"
+
+	var out bytes.Buffer
+	repl.Start(strings.NewReader(input), &out)
+
+	if !strings.Contains(out.String(), "expected } to close the block, got EOF instead") {
+		t.Errorf("expected parser error in output, got=%q", out.String())
+	}
+	if !strings.HasSuffix(out.String(), ">> 1\n>> ") {
+		t.Errorf("expected REPL to go on after the error, got=%q", out.String())
+	}
+}
+
+func TestParserErrorsAreReported(t *testing.T) {
+	input := "let = 1;\n5\n"
+
+	var out bytes.Buffer
+	repl.Start(strings.NewReader(input), &out)
+
+	if !strings.Contains(out.String(), "\t1:5: expected next token to be IDENT, got = instead\n") {
+		t.Errorf("expected parser error in output, got=%q", out.String())
+	}
+	if !strings.HasSuffix(out.String(), ">> 5\n>> ") {
+		t.Errorf("expected REPL to go on after the error, got=%q", out.String())
+	}
+}
+
+func TestMacrosAcrossLines(t *testing.T) {
+	input := "let twice = macro(x) { quote(unquote(x) * 2) };\ntwice(21)\nlet m = macro() { 1 };\nm()\n"
+
+	expected := ">> >> 42\n>> >> ERROR: 1:1: macro must return a quote, got INTEGER\n>> "
+
+	var out bytes.Buffer
+	repl.Start(strings.NewReader(input), &out)
+
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot =%q", expected, out.String())
+	}
+}
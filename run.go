@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jolisper/monkey/compiler"
+	"github.com/jolisper/monkey/evaluator"
+	"github.com/jolisper/monkey/lexer"
+	"github.com/jolisper/monkey/object"
+	"github.com/jolisper/monkey/parser"
+	"github.com/jolisper/monkey/vm"
+)
+
+const stdinName = "<stdin>"
+
+// runFile implements the run command. The program output goes to the
+// process stdout through the puts builtin, diagnostics go to stderr.
+func runFile(args []string, stdin io.Reader, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	engine := flags.String("engine", "eval", "execution engine, eval or vm")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(stderr, "monkey: run expects exactly one file\n\n%s", usage)
+		return exitUsage
+	}
+	if *engine != "eval" && *engine != "vm" {
+		fmt.Fprintf(stderr, "monkey: unknown engine %q\n", *engine)
+		return exitUsage
+	}
+
+	name := flags.Arg(0)
+	src, err := readSource(name, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return exitInternal
+	}
+
+	return runProgram(name, src, *engine, stderr)
+}
+
+func readSource(name string, stdin io.Reader) (string, error) {
+	var src []byte
+	var err error
+
+	if name == "-" {
+		src, err = io.ReadAll(stdin)
+	} else {
+		src, err = os.ReadFile(name)
+	}
+
+	return string(src), err
+}
+
+func runProgram(name, src, engine string, stderr io.Writer) int {
+	if name == "-" {
+		name = stdinName
+	}
+
+	l := lexer.NewWithFilename(name, src)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, err := range p.Errors() {
+			fmt.Fprintln(stderr, err)
+		}
+		return exitError
+	}
+
+	// Macros are expanded the same way for both engines, by the evaluator
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	if _, errObj := evaluator.ExpandMacros(program, macroEnv); errObj != nil {
+		fmt.Fprintln(stderr, errObj.Inspect())
+		return exitError
+	}
+
+	if engine == "vm" {
+		// Compile errors are runtime errors of the evaluator, printed the same
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			fmt.Fprintf(stderr, "ERROR: %s\n", err)
+			return exitError
+		}
+
+		machine := vm.New(comp.Bytecode())
+		if err := machine.Run(); err != nil {
+			fmt.Fprintf(stderr, "ERROR: %s\n", err)
+			return exitError
+		}
+
+		return exitOK
+	}
+
+	// The program is loading, as a module, while it runs: importing it back
+	// is an import cycle
+	env := object.NewEnvironment()
+	if name != stdinName {
+		if path, err := filepath.Abs(name); err == nil {
+			env.Modules().StartLoading(path)
+			defer env.Modules().DoneLoading()
+		}
+	}
+
+	evaluated := evaluator.Eval(program, env)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(stderr, errObj.Inspect())
+		return exitError
+	}
+
+	return exitOK
+}
@@ -0,0 +1,27 @@
+package vm
+
+import (
+	"github.com/jolisper/monkey/code"
+	"github.com/jolisper/monkey/object"
+	"github.com/jolisper/monkey/token"
+)
+
+// Frame is the call frame of a closure being executed.
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int // stack pointer before the call, locals live above it
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
+
+// Position returns the source position of the instruction being executed.
+func (f *Frame) Position() token.Position {
+	return f.cl.Fn.Positions.Lookup(f.ip)
+}
@@ -0,0 +1,425 @@
+package vm_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jolisper/monkey/compiler"
+	"github.com/jolisper/monkey/evaluator"
+	"github.com/jolisper/monkey/lexer"
+	"github.com/jolisper/monkey/object"
+	"github.com/jolisper/monkey/parser"
+	"github.com/jolisper/monkey/vm"
+)
+
+// Every input is run through both the vm and the evaluator, and the results
+// are expected to match each other and the given rendering. Errors are
+// rendered as "ERROR: <message>".
+func TestAgainstEvaluator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// integers and booleans
+		{"1", "1"},
+		{"1 + 2 * 3 - 4 / 2", "5"},
+		{"-(5 + 5) * 2", "-20"},
+		{"1 < 2", "true"},
+		{"1 > 2", "false"},
+		{"1 == 1", "true"},
+		{"1 != 1", "false"},
+		{"true == true", "true"},
+		{"(1 < 2) == false", "false"},
+		{"!true", "false"},
+		{"!!5", "true"},
+		{"!(if (false) { 5; })", "true"},
+		{"true && 1", "true"},
+		{"1 > 2 && 1", "false"},
+		{"0 || false", "true"},
+		{"false || if (false) { 1 }", "false"},
+		{"let f = fn() { f() }; true || f()", "true"},
+		{"7 % 3", "1"},
+		{"2 ** 3 ** 2", "512"},
+		{"2 ** 64", "18446744073709551616"},
+		{"2 ** -2", "0.25"},
+		{"6 & 3 | 8 ^ 1", "11"},
+		{"~5", "-6"},
+		{"~(1 << 64)", "-18446744073709551617"},
+		{"1 << 63", "9223372036854775808"},
+		{"-16 >> 2", "-4"},
+		{"(1 << 70) >> 69", "2"},
+		{"2 <= 2", "true"},
+		{"2 >= 3", "false"},
+		{"1.5 >= 1", "true"},
+		{"-7 % 3", "-1"},
+
+		// floats
+		{"1.5 + 1", "2.5"},
+		{"-0.5 * 4", "-2.0"},
+		{"7 / 2", "3"},
+		{"7 / 2.0", "3.5"},
+		{"1 < 1.5", "true"},
+		{"2 == 2.0", "true"},
+		{"7.5 % 2", "1.5"},
+
+		// big integers
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"9223372036854775807 + 1 - 1", "9223372036854775807"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"4294967296 * 4294967296 / 3", "6148914691236517205"},
+		{"123456789012345678901234567890 > 1", "true"},
+		{"123456789012345678901234567890 % 11", "7"},
+
+		// conditionals
+		{"if (true) { 10 }", "10"},
+		{"if (1 > 2) { 10 }", "null"},
+		{"if (1 > 2) { 10 } else { 20 }", "20"},
+		{"if (0) { 10 } else { 20 }", "10"},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", "20"},
+
+		// globals
+		{"let one = 1; let two = one + one; one + two", "3"},
+		{"let x = 1; let x = x + 1; x", "2"},
+
+		// strings
+		{`"mon" + "key"`, "monkey"},
+		{`"a" == "a"`, "true"},
+		{`"a" != "a"`, "false"},
+
+		// collections
+		{"[1, 2 * 2, 3 + 3]", "[1, 4, 6]"},
+		{"[1, 2, 3][1]", "2"},
+		{"[1, 2, 3][-1]", "3"},
+		{"[1, 2, 3][3]", "null"},
+		{`{"b": 2, "a": 1, 3: true}`, "{3: true, a: 1, b: 2}"},
+		{`{"a": 1, "a": 2}["a"]`, "2"},
+		{`{}["missing"]`, "null"},
+
+		// functions and closures
+		{"let f = fn() { 5 + 10; }; f();", "15"},
+		{"let f = fn() { return 99; 100; }; f();", "99"},
+		{"let f = fn() { }; f();", "null"},
+		{"let sum = fn(a, b) { let c = a + b; c; }; sum(1, 2) + sum(3, 4);", "10"},
+		{"let newAdder = fn(a) { fn(b) { a + b } }; newAdder(2)(3);", "5"},
+		{`
+let newAdderOuter = fn(a, b) {
+	let c = a + b;
+	fn(d) {
+		let e = d + c;
+		fn(f) { e + f; };
+	};
+};
+let newAdderInner = newAdderOuter(1, 2);
+let adder = newAdderInner(3);
+adder(8);`, "14"},
+		{`
+let fibonacci = fn(x) {
+	if (x == 0) { return 0; }
+	if (x == 1) { return 1; }
+	fibonacci(x - 1) + fibonacci(x - 2);
+};
+fibonacci(15);`, "610"},
+		{`
+let wrapper = fn() {
+	let countDown = fn(x) {
+		if (x == 0) { return 0; }
+		countDown(x - 1);
+	};
+	countDown(1);
+};
+wrapper();`, "0"},
+		{`
+let map = fn(arr, f) {
+	let iter = fn(arr, accumulated) {
+		if (len(arr) == 0) {
+			accumulated
+		} else {
+			iter(rest(arr), push(accumulated, f(first(arr))));
+		}
+	};
+	iter(arr, []);
+};
+map([1, 2, 3], fn(x) { x * 2 });`, "[2, 4, 6]"},
+		{"return 10; 9;", "10"},
+		{"if (10 > 1) { if (10 > 1) { return 10; } return 1; }", "10"},
+
+		// loops
+		{"let i = 0; while (i < 5) { let i = i + 1; }; i", "5"},
+		{"let sum = 0; for (x in [1, 2, 3]) { let sum = sum + x; }; sum", "6"},
+		{`let s = ""; for (c in "añb") { let s = c + s; }; s`, "bña"},
+		{`let s = ""; for (k in {"b": 1, "a": 2}) { let s = s + k; }; s`, "ab"},
+		{"let i = 0; while (true) { let i = i + 1; if (i == 3) { break; } }; i", "3"},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x % 2 == 0) { continue; } let sum = sum + x; }; sum", "4"},
+		{`
+let count = fn(limit) {
+	let n = 0;
+	for (x in [1, 2, 3]) {
+		for (y in [1, 2, 3]) {
+			if (y > x) { break; }
+			let n = n + 1;
+		}
+		if (n > limit) { break; }
+	}
+	n
+};
+count(100) + count(2)`, "9"},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10; } } 0 }; f()", "20"},
+		{"let f = fn() { while (false) { } }; f()", "null"},
+		// loop variables are not block scoped, closures see the last value
+		{"let fs = []; for (x in [1, 2]) { let fs = push(fs, fn() { x }); }; fs[0]() + fs[1]()", "4"},
+		{"let x = 1; let f = fn() { x }; let x = 2; f()", "2"},
+		{"let f = fn() { let fns = []; for (i in [1, 2, 3]) { fns = push(fns, fn() { i }); } fns[0]() }; f()", "3"},
+		{"for (x in 5) { }", "ERROR: not iterable: INTEGER"},
+		// break and continue inside expressions leave their operands behind
+		{"let out = []; let i = 0; while (i < 3) { i += 1; let a = if (i == 1) { break; } else { i }; out = push(out, a); }; [i, out]", "[1, []]"},
+		{"let out = []; for (x in [1, 2, 3]) { out = push(out, if (x == 2) { continue; } else { x }); }; out", "[1, 3]"},
+		{"let i = 0; let n = 0; while (i < 5000) { i += 1; n += len(if (true) { continue; } else { [] }); }; [i, n]", "[5000, 0]"},
+		{"let r = 0; while (true) { r = 1 + [2, if (r == 0) { break; } else { 3 }][0]; }; r", "0"},
+		{`let h = {}; for (x in [1, 2]) { h[x] = {"k": if (x == 1) { continue; } else { x }}; }; h`, "{2: {k: 2}}"},
+		{"let f = fn() { let a = [1, if (true) { return 2; }]; 3 }; f()", "2"},
+
+		// assignment
+		{"let a = 1; a = 2", "2"},
+		{"let a = 1; let b = 2; a = b = 3; a + b", "6"},
+		{"let i = 0; let sum = 0; while (i < 5) { i += 1; sum += i; }; sum", "15"},
+		{"let a = 10; a -= 3; a *= 2; a **= 2; a %= 100; a", "96"},
+		{"let a = 6; a &= 3; a |= 8; a ^= 1; a <<= 2; a >>= 1; a", "22"},
+		{"let a = 1; let f = fn() { a += 1 }; f(); f(); a", "3"},
+		{"let f = fn() { let n = 1; n *= 5; n }; f()", "5"},
+		// closures share the variables they capture with the enclosing function
+		{"let g = fn() { let n = 1; let h = fn() { n }; n = 5; h() }; g()", "5"},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", "3"},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let a = counter(); let b = counter(); a(); a(); b()", "1"},
+		{"let pair = fn() { let n = 0; [fn() { n += 1 }, fn() { n }] }; let p = pair(); p[0](); p[0](); p[1]()", "2"},
+		{"let f = fn() { let n = 1; let g = fn() { fn() { n *= 10 } }; g()(); n }; f()", "10"},
+		{"let f = fn() { let y = 1; fn() { y } }; let a = f(); let h = fn() { let z = 9; z }; h(); a()", "1"},
+		// a function refers to its own name like to any captured variable
+		{"let f = fn() { f = 1 }; f(); f", "1"},
+		{"let g = fn() { let f = fn() { f = 5; 1 }; f(); f }; g()", "5"},
+		{"let g = fn() { let f = fn(n) { if (n == 0) { 0 } else { n + f(n - 1) } }; f(3) }; g()", "6"},
+		{"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; let g = f; f = fn(n) { 42 }; g(1)", "42"},
+		{"let arr = [1, 2, 3]; arr[0] = 5; arr[-1] += 10; arr", "[5, 2, 13]"},
+		{`let h = {"a": 1}; h["a"] += 1; h["b"] = 5; h["a"] + h["b"]`, "7"},
+		{"let arr = [[1]]; arr[0][0] += 1; arr", "[[2]]"},
+		{"let a = 1; a += true", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+
+		// constants
+		{"const a = 5; let f = fn() { let a = 6; a }; f() + a", "11"},
+		{"const a = 5; let f = fn(a) { a += 1 }; f(1)", "2"},
+		{"const arr = [1]; arr[0] = 2; arr", "[2]"},
+		{"let a = 1; const a = 2; a", "2"},
+		{"let s = 0; for (x in [1, 2, 3]) { const c = x * 2; s += c; }; s", "12"},
+		{"let i = 0; while (i < 3) { i += 1; if (true) { const k = i; } }; k", "3"},
+		{"let f = fn() { let s = 0; for (x in [1, 2, 3]) { const c = x * 2; s += c; } s }; f()", "12"},
+		{"let arr = [1]; arr[1] = 2", "ERROR: index out of range: 1"},
+		{"let arr = [1]; arr[0] += true", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{`let h = {}; h[[1]] = 2`, "ERROR: unusable as hash key: ARRAY"},
+		{`let s = "ab"; s[0] = "c"`, "ERROR: index assignment not supported: STRING[INTEGER]"},
+
+		// builtins
+		{`len("four")`, "4"},
+		{`len([1, 2])`, "2"},
+		{`first([])`, "null"},
+		{`puts()`, "null"},
+
+		// errors
+		{"5 + true;", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "ERROR: unknown operator: -BOOLEAN"},
+		{"1.5 + true", "ERROR: type mismatch: FLOAT + BOOLEAN"},
+		{"true + false;", "ERROR: unknown operator: BOOLEAN + BOOLEAN"},
+		{`"a" - "b"`, "ERROR: unknown operator: STRING - STRING"},
+		{"if (10 > 1) { true + false; }", "ERROR: unknown operator: BOOLEAN + BOOLEAN"},
+		{"let x = 1; x();", "ERROR: not a function: INTEGER"},
+		{"fn(a) { a; }();", "ERROR: wrong number of arguments: want=1, got=0"},
+		{"[1][true]", "ERROR: index operator not supported: ARRAY[BOOLEAN]"},
+		{"{[1]: 2}", "ERROR: unusable as hash key: ARRAY"},
+		{`len(1)`, "ERROR: argument to `len` not supported, got INTEGER"},
+		{"1 / 0", "ERROR: division by zero"},
+		{"1 << -1", "ERROR: negative shift count: -1"},
+		{"1 << 99999999999999999999", "ERROR: integer too large"},
+		{"1.5 | 1", "ERROR: unknown operator: FLOAT | INTEGER"},
+		{"~1.5", "ERROR: unknown operator: ~FLOAT"},
+		{"let n = 0; 10 % n", "ERROR: division by zero"},
+		{"123456789012345678901234567890 / 0", "ERROR: division by zero"},
+	}
+
+	for _, tt := range tests {
+		vmResult := runVM(t, tt.input)
+		evalResult := runEvaluator(t, tt.input)
+
+		if vmResult != tt.expected {
+			t.Errorf("vm result wrong for %q. want=%q, got=%q", tt.input, tt.expected, vmResult)
+		}
+		if evalResult != tt.expected {
+			t.Errorf("evaluator result wrong for %q. want=%q, got=%q", tt.input, tt.expected, evalResult)
+		}
+	}
+}
+
+// Inputs using as many constants, variables, arguments or instructions as
+// the operands of their instructions can address.
+func TestOperandLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn() { " + list(256, "let a%d = %d;", " ") + " a0 + a255 }()", "255"},
+		{list(65536, "let a%d = %d;", " ") + " a0 + a65535", "65535"},
+		{list(65536, "%d;", " "), "65535"},
+		{"fn(" + list(255, "a%d", ", ") + ") { a0 + a254 }(" + list(255, "%d", ", ") + ")", "254"},
+		{"len([" + list(65535, "true", ", ") + "])", "65535"},
+		{"{" + list(32767, "%d: true", ", ") + "}[32766]", "true"},
+		{"fn() { " + list(256, "let a%d = %d;", " ") + " fn() { " + list(255, "a%d", " + ") + " } }()()", "32385"},
+		{"if (true) { " + list(32764, "true", "; ") + " }", "true"},
+	}
+
+	for _, tt := range tests {
+		vmResult := runVM(t, tt.input)
+		evalResult := runEvaluator(t, tt.input)
+
+		if vmResult != tt.expected {
+			t.Errorf("vm result wrong for input %d bytes long. want=%q, got=%q", len(tt.input), tt.expected, vmResult)
+		}
+		if evalResult != tt.expected {
+			t.Errorf("evaluator result wrong for input %d bytes long. want=%q, got=%q", len(tt.input), tt.expected, evalResult)
+		}
+	}
+}
+
+// Returns n items, the i-th formatted with format, which may use i at most
+// twice, separated by sep.
+func list(n int, format, sep string) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = strings.ReplaceAll(format, "%d", strconv.Itoa(i))
+	}
+	return strings.Join(items, sep)
+}
+
+// Runtime errors are located at the same node by both engines.
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + true", "ERROR: 1:1: type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 5;\nx * -true", "ERROR: 2:5: unknown operator: -BOOLEAN"},
+		{"let f = fn(a) {\n  a / 0\n};\nf(1)", "ERROR: 2:3: division by zero"},
+		{"let g = fn(x) { x + true };\nlet f = fn() { g(1) };\nf()", "ERROR: 1:17: type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn() { 1 };\nf(1)", "ERROR: 2:1: wrong number of arguments: want=0, got=1"},
+		{"let x = 1;\n[x, x()]", "ERROR: 2:5: not a function: INTEGER"},
+		{"len(1)", "ERROR: 1:1: argument to `len` not supported, got INTEGER"},
+		{"if (true) {\n  push(1, 2)\n}", "ERROR: 2:3: argument to `push` must be ARRAY, got INTEGER"},
+		{"[1, 2][true]", "ERROR: 1:1: index operator not supported: ARRAY[BOOLEAN]"},
+		{"let a = [1];\na[5] = 1", "ERROR: 2:1: index out of range: 5"},
+		{"let a = [1];\na[0] += true", "ERROR: 2:1: type mismatch: INTEGER + BOOLEAN"},
+		{"let a = 1;\na -= \"s\"", "ERROR: 2:1: type mismatch: INTEGER - STRING"},
+		{"for (x in [1]) {\n  for (y in x) { }\n}", "ERROR: 2:13: not iterable: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vmResult := "no error"
+		if err := vm.New(comp.Bytecode()).Run(); err != nil {
+			vmResult = "ERROR: " + err.Error()
+		}
+
+		evalResult := "no error"
+		if errObj, ok := evaluator.Eval(program, object.NewEnvironment()).(*object.Error); ok {
+			evalResult = errObj.Inspect()
+		}
+
+		if vmResult != tt.expected {
+			t.Errorf("vm error wrong for %q. want=%q, got=%q", tt.input, tt.expected, vmResult)
+		}
+		if evalResult != tt.expected {
+			t.Errorf("evaluator error wrong for %q. want=%q, got=%q", tt.input, tt.expected, evalResult)
+		}
+	}
+}
+
+func TestRecursionDepth(t *testing.T) {
+	input := `let f = fn(x) { if (x == 0) { 0 } else { f(x - 1) } }; f(100000);`
+
+	if result := runVM(t, input); result != "ERROR: stack overflow" {
+		t.Errorf("expected stack overflow, got=%q", result)
+	}
+}
+
+func runVM(t *testing.T, input string) string {
+	t.Helper()
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	machine := vm.New(comp.Bytecode())
+	if err := machine.Run(); err != nil {
+		return "ERROR: " + err.(*vm.Error).Message
+	}
+
+	return machine.LastPoppedStackElem().Inspect()
+}
+
+func runEvaluator(t *testing.T, input string) string {
+	t.Helper()
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	result := evaluator.Eval(program, object.NewEnvironment())
+	if errObj, ok := result.(*object.Error); ok {
+		return "ERROR: " + errObj.Message
+	}
+	if result == nil {
+		return "null"
+	}
+
+	return result.Inspect()
+}
+
+const fibonacciInput = `
+let fibonacci = fn(x) {
+	if (x < 2) { return x; }
+	fibonacci(x - 1) + fibonacci(x - 2);
+};
+fibonacci(20);`
+
+func BenchmarkFibonacciVM(b *testing.B) {
+	program := parser.New(lexer.New(fibonacciInput)).ParseProgram()
+
+	for i := 0; i < b.N; i++ {
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			b.Fatalf("compiler error: %s", err)
+		}
+
+		machine := vm.New(comp.Bytecode())
+		if err := machine.Run(); err != nil {
+			b.Fatalf("vm error: %s", err)
+		}
+	}
+}
+
+func BenchmarkFibonacciEvaluator(b *testing.B) {
+	program := parser.New(lexer.New(fibonacciInput)).ParseProgram()
+
+	for i := 0; i < b.N; i++ {
+		evaluator.Eval(program, object.NewEnvironment())
+	}
+}